@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"housekeeper/internal/jobs/purge"
+)
+
+// runConfig runs the config subcommands
+func runConfig(ctx context.Context, args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "validate":
+			return runConfigValidate(ctx, args[1:])
+		case "show":
+			return runConfigShow(ctx, args[1:])
+		case "migrate":
+			return runConfigMigrate(ctx, args[1:])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Usage: housekeeper config <validate|show|migrate> [flags]")
+	fmt.Fprintln(os.Stderr, "\n  validate  Load the config and report any problem")
+	fmt.Fprintln(os.Stderr, "  show      Print the config in effect as a config file")
+	fmt.Fprintln(os.Stderr, "  migrate   Convert the legacy config files into a config file")
+	return exitError
+}
+
+// runConfigValidate loads the config the other commands would use and
+// lists the problems ValidateConfig finds with it
+func runConfigValidate(_ context.Context, args []string) int {
+	fs := newFlagSet("config validate", "",
+		"Loads the config and reports any problem: invalid patterns, entries missing\n"+
+			"their leading dot, case conflicts, renames of deleted files, unreachable\n"+
+			"rules, and replacement chains or cycles. Exits 2 on errors, 1 on warnings.")
+	dir := fs.String("dir", ".", "Directory whose config to validate")
+	asJSON := fs.Bool("json", false, "Print the diagnostics as JSON")
+	loadConfig := configFlags(fs, dir)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	var diags []purge.Diagnostic
+	cfg, loc, err := loadConfig()
+	var invalid *purge.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		diags = invalid.Diagnostics
+	case err != nil:
+		// Not read at all, so there is nothing to diagnose
+		fmt.Fprintf(os.Stderr, "Config is invalid: %v\n", err)
+		return exitError
+	default:
+		diags = cfg.Warnings
+	}
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, diagnosticsOrEmpty(diags)); err != nil {
+			return fail("Failed to write diagnostics: %v", err)
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Println(d)
+		}
+	}
+
+	switch {
+	case invalid != nil:
+		if !*asJSON {
+			fmt.Fprintf(os.Stderr, "Config is invalid: %s\n", describeLocation(loc))
+		}
+		return exitError
+	case len(diags) > 0:
+		if !*asJSON {
+			fmt.Fprintf(os.Stderr, "Config has %d warning(s): %s\n", len(diags), describeLocation(loc))
+		}
+		return exitChanges
+	}
+	if !*asJSON {
+		fmt.Printf("Config is valid: %s\n", describeLocation(loc))
+	}
+	return exitOK
+}
+
+// diagnosticsOrEmpty keeps JSON output a list when there is no diagnostic
+func diagnosticsOrEmpty(diags []purge.Diagnostic) []purge.Diagnostic {
+	if diags == nil {
+		return []purge.Diagnostic{}
+	}
+	return diags
+}
+
+// runConfigShow prints the config the other commands would use, with all
+// files merged
+func runConfigShow(_ context.Context, args []string) int {
+	fs := newFlagSet("config show", "",
+		"Prints the config in effect as a config file, with all files merged, or with\n"+
+			"-origin, every setting with the file it came from.")
+	dir := fs.String("dir", ".", "Directory whose config to show")
+	origin := fs.Bool("origin", false, "List every setting with the file it came from")
+	loadConfig := configFlags(fs, dir)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	cfg, loc, err := loadConfig()
+	if err != nil {
+		return fail("Failed to load config: %v", err)
+	}
+	if *origin {
+		printOrigins(loc, cfg.Origins())
+		return exitOK
+	}
+	if err := writeJSON(os.Stdout, purge.NewConfigFile(cfg)); err != nil {
+		return fail("Failed to write config: %v", err)
+	}
+	return exitOK
+}
+
+// runConfigMigrate converts the legacy config files into a config file
+func runConfigMigrate(_ context.Context, args []string) int {
+	fs := newFlagSet("config migrate", "",
+		"Converts the legacy config files (userconfigs/extensions_to_delete.json,\n"+
+			"userconfigs/extension_replacements.json, userconfigs/delete_rules.json and\n"+
+			"config.json) into a single "+purge.DirConfigName+".")
+	out := fs.String("o", purge.DirConfigName, "Config file to write (- for stdout)")
+	force := fs.Bool("force", false, "Overwrite the config file if it exists")
+	dir := fs.String("dir", ".", "Directory holding the legacy files")
+	legacy := legacyConfigFlags(fs, "")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	opts := legacy()
+	opts.Dir = *dir
+	file, err := purge.MigrateConfig(opts)
+	if err != nil {
+		return fail("Failed to migrate config: %v", err)
+	}
+
+	if *out == "-" {
+		if err := writeJSON(os.Stdout, file); err != nil {
+			return fail("Failed to write config: %v", err)
+		}
+		return exitOK
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		return fail("%s already exists (overwrite with -force)", *out)
+	}
+	if err := purge.WriteConfigFile(*out, file); err != nil {
+		return fail("Failed to write config: %v", err)
+	}
+	fmt.Printf("Wrote %s; the legacy files are no longer read\n", *out)
+	return exitOK
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// describeLocation tells which config file is used and why
+func describeLocation(loc purge.ConfigLocation) string {
+	if loc.Path == "" {
+		return purge.BuiltinSource + " (" + loc.Found + ")"
+	}
+	return loc.Path + " (" + loc.Found + ")"
+}
+
+func printOrigins(loc purge.ConfigLocation, origins []purge.SettingOrigin) {
+	fmt.Printf("Config: %s\n\n", describeLocation(loc))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, o := range origins {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", o.Setting, o.Value, o.Source)
+	}
+	w.Flush()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"housekeeper/internal/common"
+	"housekeeper/internal/jobs/purge"
+)
+
+// runExplain tells what planning would do with a single file
+func runExplain(_ context.Context, args []string) int {
+	fs := newFlagSet("explain", "<path>",
+		"Tells whether a file is excluded, which rule plans a change for it and\n"+
+			"where the rule was configured. Rename collisions are not checked.")
+	dir := fs.String("dir", ".", "Directory the file would be scanned under")
+	asJSON := fs.Bool("json", false, "Print the explanation as JSON")
+	loadConfig := configFlags(fs, dir)
+	logging := loggingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return fail("Failed to load config: %v", err)
+	}
+	common.SetupLogging(logging(cfg.Logging))
+	warnConfig(cfg)
+
+	e, err := purge.NewJob(*dir, cfg).Explain(fs.Arg(0))
+	if err != nil {
+		return fail("Failed to explain %s: %v", fs.Arg(0), err)
+	}
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, e); err != nil {
+			return fail("Failed to write explanation: %v", err)
+		}
+	} else {
+		printExplanation(e)
+	}
+
+	if e.Change == nil {
+		return exitOK
+	}
+	return exitChanges
+}
+
+func printExplanation(e *purge.Explanation) {
+	fmt.Println(e.Path)
+	switch {
+	case e.ExcludedBy == e.Path:
+		fmt.Println("  Excluded by the config or an ignore file; it is never planned")
+	case e.ExcludedBy != "":
+		fmt.Printf("  Inside %s, which is excluded by the config or an ignore file; it is never planned\n", e.ExcludedBy)
+	case e.Change == nil:
+		fmt.Println("  No rule matches; it is kept as is")
+	default:
+		fmt.Printf("  %s\n", describeChange(*e.Change))
+		if e.Change.Reason != nil {
+			fmt.Printf("  ↳ %s\n", e.Change.Reason)
+		}
+	}
+}
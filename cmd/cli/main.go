@@ -1,88 +1,608 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
 
 	"housekeeper/internal/common"
 	"housekeeper/internal/jobs/purge"
 )
 
+// Exit codes shared by all commands
+const (
+	exitOK      = 0 // nothing to change, or everything was applied
+	exitChanges = 1 // changes were found, or warnings by config validate
+	exitError   = 2 // invalid usage, or something failed
+)
+
+// command is a housekeeper subcommand. run returns the exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) int
+}
+
+var commands = []command{
+	{"plan", "List the changes for a directory, optionally saving them to a plan file", runPlan},
+	{"apply", "Apply a plan file, or plan and apply a directory at once", runApply},
+	{"undo", "Revert the changes recorded in an apply journal", runUndo},
+	{"config", "Validate or show the config (config validate, config show)", runConfig},
+	{"explain", "Tell which rule, if any, plans a change for a file", runExplain},
+	{"version", "Print the version", runVersion},
+}
+
 func main() {
-	dir := flag.String("dir", ".", "Directory to scan")
-	exts := flag.String("exts", "userconfigs/extensions_to_delete.json", "Delete config")
-	repls := flag.String("repls", "userconfigs/extension_replacements.json", "Replace config")
-	apply := flag.Bool("apply", false, "Apply changes (default is dry run)")
-	logToFile := flag.Bool("log-to-file", true, "Enable file-based logging")
-	logPath := flag.String("log-path", "logs/toolkit.log", "Path to log file")
-	debugLogs := flag.Bool("debug", false, "Enable debug-level logs")
-	alsoPrint := flag.Bool("also-print-to-console", true, "Also print logs to console when logging to file")
-	flag.Parse()
-
-	common.SetupLogging(common.LoggingConfig{
-		LogToFile:          *logToFile,
-		LogFilePath:        *logPath,
-		Debug:              *debugLogs,
-		AlsoPrintToConsole: *alsoPrint, // if logging to file, default no; or set manually
-	})
+	// Ctrl-C stops planning, or applying between changes, with a summary
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitError
+	}
+
+	name, args := args[0], args[1:]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return exitOK
+	}
+	if strings.HasPrefix(name, "-") {
+		return runLegacy(ctx, append([]string{name}, args...))
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(ctx, args)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "housekeeper: unknown command %q\n\n", name)
+	usage()
+	return exitError
+}
 
-	cfg, err := purge.LoadConfigWithOptions(purge.LoadConfigOptions{
-		DeleteConfigPath:  *exts,
-		ReplaceConfigPath: *repls,
+func usage() {
+	w := os.Stderr
+	fmt.Fprintln(w, "Usage: housekeeper <command> [flags] [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun housekeeper <command> -h for the flags of a command.")
+	fmt.Fprintln(w, "\nExit codes: 0 no changes (or all applied), 1 changes found, 2 errors.")
+}
+
+// runLegacy runs the flags-only invocation from before subcommands, where
+// -apply picked between a dry run and applying
+func runLegacy(ctx context.Context, args []string) int {
+	apply := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg) // a flag value, e.g. -dir apply
+			continue
+		}
+		switch strings.TrimLeft(arg, "-") {
+		case "apply", "apply=true":
+			apply = true
+		case "apply=false":
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	if apply {
+		fmt.Fprintln(os.Stderr, "housekeeper: -apply is deprecated, use: housekeeper apply -dir <dir>")
+		return runApply(ctx, rest)
+	}
+	fmt.Fprintln(os.Stderr, "housekeeper: running without a command is deprecated, use: housekeeper plan -dir <dir>")
+	return runPlan(ctx, rest)
+}
+
+// newFlagSet creates the flag set of a command with its usage line
+func newFlagSet(name, args, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: housekeeper %s [flags] %s\n\n%s\n\nFlags:\n", name, args, summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseError returns the exit code for a flag set that failed to parse;
+// the flag package has already printed the error and the usage
+func parseError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitError
+}
+
+// flagSet reports whether the flag name was given on the command line
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
 	})
+	return set
+}
+
+// fail logs why a command failed and returns exitError
+func fail(format string, args ...any) int {
+	log.Printf(format, args...)
+	return exitError
+}
+
+// runPlan lists the changes for a directory, and writes them to a plan file
+// for a later apply if asked to
+func runPlan(ctx context.Context, args []string) int {
+	fs := newFlagSet("plan", "", "Lists the changes for a directory without making them.")
+	dir := fs.String("dir", ".", "Directory to scan")
+	planPath := fs.String("o", "", "Also write the changes to a plan file for apply (- for stdout)")
+	workers := fs.Int("workers", 0, "Directories to read in parallel (default: based on CPU count)")
+	hash := fs.Bool("hash", false, "Also fingerprint file contents so apply detects any edit")
+	loadConfig := configFlags(fs, dir)
+	newOutput := outputFlags(fs)
+	logging := loggingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	// A plan on stdout leaves the listing to stderr
+	listing := io.Writer(os.Stdout)
+	if *planPath == "-" {
+		listing = os.Stderr
+	}
+	out, err := newOutput(listing)
+	if err != nil {
+		return fail("Invalid output flags: %v", err)
+	}
+
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return fail("Failed to load config: %v", err)
+	}
+	common.SetupLogging(consoleLogging(logging(cfg.Logging), out))
+	warnConfig(cfg)
+
+	// -hash only changes how the plan is made, so the plan file keeps the
+	// config hash apply compares against
+	planCfg := *cfg
+	planCfg.FingerprintHash = cfg.FingerprintHash || *hash
+
+	// Print changes as they are found; only keep them for a plan file
+	job := purge.NewJob(*dir, &planCfg)
+	job.Workers = *workers
+	progress := newProgressLine(0)
+	job.Progress = progress.observer()
+	var changes []purge.Change
+	found, toApply := 0, 0
+	for change, err := range job.PlanSeq(ctx) {
+		if err != nil {
+			progress.finish()
+			out.close()
+			return fail("Error during plan: %v", err)
+		}
+		found++
+		if !change.Skipped() {
+			toApply++
+		}
+		progress.clear()
+		out.change(found, change)
+		if *planPath != "" {
+			changes = append(changes, change)
+		}
+	}
+	progress.finish()
+	if err := out.close(); err != nil {
+		return fail("Failed to write output: %v", err)
+	}
+	out.notef("\nFound %d changes\n", found)
+
+	if *planPath != "" {
+		if err := writePlan(*planPath, *dir, cfg, changes); err != nil {
+			return fail("Failed to write plan: %v", err)
+		}
+		if *planPath != "-" {
+			out.notef("Wrote %d changes to %s (apply with: housekeeper apply %s)\n", found, *planPath, *planPath)
+		}
+	}
+
+	// Renames skipped over a collision would change nothing
+	if toApply == 0 {
+		return exitOK
+	}
+	return exitChanges
+}
+
+// writePlan writes the changes planned for dir to a plan file, or to stdout
+// for path "-"
+func writePlan(path, dir string, cfg *purge.Config, changes []purge.Change) error {
+	plan, err := purge.NewPlanFile(dir, cfg, changes)
+	if err != nil {
+		return err
+	}
+	if path != "-" {
+		return purge.WritePlan(path, plan)
+	}
+	return writeJSON(os.Stdout, plan)
+}
+
+// runApply executes exactly the changes in a plan file, or plans a
+// directory and applies the changes right away
+func runApply(ctx context.Context, args []string) int {
+	fs := newFlagSet("apply", "[plan.json]",
+		"Applies the changes in a plan file written by plan -o. Without a plan file,\n"+
+			"plans the directory given by -dir and applies the changes found.")
+	dir := fs.String("dir", ".", "Directory to plan and apply without a plan file, and whose config applies (default with a plan file: its root)")
+	workers := fs.Int("workers", 0, "Directories to read in parallel (default: based on CPU count)")
+	loadConfig := configFlags(fs, dir)
+	applyOptions := applyFlags(fs)
+	newOutput := outputFlags(fs)
+	logging := loggingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+	out, err := newOutput(os.Stdout)
+	if err != nil {
+		return fail("Invalid output flags: %v", err)
+	}
+	opts, err := applyOptions()
+	if err != nil {
+		return fail("Invalid apply flags: %v", err)
+	}
+
+	var plan *purge.PlanFile
+	if fs.NArg() == 1 {
+		if plan, err = purge.ReadPlan(fs.Arg(0)); err != nil {
+			return fail("Failed to read plan: %v", err)
+		}
+		if !flagSet(fs, "dir") {
+			*dir = plan.Root
+		}
+	}
+
+	// A plan file has its changes, but the config still sets the limits
+	cfg, _, err := loadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fail("Failed to load config: %v", err)
 	}
+	common.SetupLogging(consoleLogging(logging(cfg.Logging), out))
+	warnConfig(cfg)
+	opts.Limits = cfg.Safety
 
+	if plan != nil {
+		changed, err := plan.ConfigChanged(cfg)
+		if err != nil {
+			return fail("Failed to check the plan's config: %v", err)
+		}
+		if changed && opts.Strict {
+			return fail("Plan %s was made with a different config, nothing applied", fs.Arg(0))
+		}
+		if changed {
+			common.Warn.Printf("Plan %s was made with a different config than the current one", fs.Arg(0))
+		}
+		out.notef("Applying plan for %s made %s (%d changes)\n",
+			plan.Root, plan.CreatedAt.Local().Format(time.DateTime), len(plan.Changes))
+		return applyChanges(ctx, plan.Changes, opts, out)
+	}
+
+	// Print changes as they are found, then apply them all
 	job := purge.NewJob(*dir, cfg)
-	changes, err := job.Plan()
+	job.Workers = *workers
+	progress := newProgressLine(0)
+	job.Progress = progress.observer()
+	var changes []purge.Change
+	for change, err := range job.PlanSeq(ctx) {
+		if err != nil {
+			progress.finish()
+			out.close()
+			return fail("Error during plan: %v", err)
+		}
+		changes = append(changes, change)
+		progress.clear()
+		out.change(len(changes), change)
+	}
+	progress.finish()
+	out.notef("\nFound %d changes\n", len(changes))
+	return applyChanges(ctx, changes, opts, out)
+}
+
+// applyChanges applies changes with a journal, writes the outcome to out
+// and returns the exit code
+func applyChanges(ctx context.Context, changes []purge.Change, opts purge.ApplyOptions, out *output) int {
+	if opts.JournalPath == "" {
+		path, err := purge.DefaultJournalPath(time.Now())
+		if err != nil {
+			out.close()
+			return fail("Failed to locate journal: %v", err)
+		}
+		opts.JournalPath = path
+	}
+
+	toApply := 0
+	for _, change := range changes {
+		if !change.Skipped() {
+			toApply++
+		}
+	}
+	progress := newProgressLine(toApply)
+	opts.Progress = progress.observer()
+	results, err := purge.ApplyAllContext(ctx, changes, opts)
+	progress.finish()
+	if results == nil && err != nil {
+		out.close()
+		return fail("Error during apply: %v", err) // nothing was attempted
+	}
+
+	counts := make(map[purge.ApplyStatus]int)
+	for i, r := range results {
+		counts[r.Status]++
+		out.result(i+1, r)
+	}
+	if err := out.close(); err != nil {
+		return fail("Failed to write output: %v", err)
+	}
+	out.notef("\nApplied %d, skipped %d, failed %d, pending %d; freed %s\n",
+		counts[purge.StatusApplied], counts[purge.StatusSkipped], counts[purge.StatusFailed],
+		counts[purge.StatusPending], formatBytes(results.BytesFreed()))
+
+	if counts[purge.StatusApplied] > 0 {
+		out.notef("Journal written to %s (revert with: housekeeper undo %s)\n", opts.JournalPath, opts.JournalPath)
+	}
+	var interrupted *purge.InterruptedError
+	if errors.As(err, &interrupted) {
+		return fail("Interrupted: %v", interrupted)
+	}
 	if err != nil {
-		log.Fatalf("Error during plan: %v", err)
+		return fail("Apply finished with errors, see above")
 	}
+	return exitOK
+}
+
+// printResult prints the outcome of one change and why it was not applied
+func printResult(w io.Writer, i int, r purge.ApplyResult) {
+	fmt.Fprintf(w, "%2d. [%s] %s", i, strings.ToUpper(string(r.Status)), describeChange(r.Change))
+	switch {
+	case r.Err != nil:
+		fmt.Fprintf(w, "\n                 ↳ %v", r.Err)
+	case r.Status == purge.StatusSkipped && r.Change.Collision != nil:
+		fmt.Fprintf(w, "\n                 ↳ collides with %s", r.Change.Collision.With)
+	case r.Status == purge.StatusApplied && r.BytesFreed > 0:
+		fmt.Fprintf(w, " (%s freed)", formatBytes(r.BytesFreed))
+	}
+	fmt.Fprintln(w)
+}
 
-	fmt.Printf("Found %d changes:\n", len(changes))
-	for i, change := range changes {
-		printChange(i+1, change)
+// runUndo reverts the changes recorded in a journal written by an apply
+func runUndo(_ context.Context, args []string) int {
+	fs := newFlagSet("undo", "<journal>", "Reverts the changes recorded in a journal written by apply.")
+	logging := loggingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
 	}
 
-	if !*apply {
-		fmt.Println("\nNo changes applied (use -apply to execute)")
-		return
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
 	}
+	common.SetupLogging(logging(common.LoggingSettings{}))
 
-	applied, err := purge.ApplyAll(changes)
+	reverted, err := purge.Undo(fs.Arg(0))
+	fmt.Printf("Reverted %d changes:\n", len(reverted))
+	for i, change := range reverted {
+		fmt.Printf("%2d. [REVERTED] %s\n", i+1, describeChange(change))
+	}
 	if err != nil {
-		log.Fatalf("Error during apply: %v", err)
+		return fail("Error during undo: %v", err)
+	}
+	return exitOK
+}
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+func runVersion(_ context.Context, args []string) int {
+	fs := newFlagSet("version", "", "Prints the version.")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	fmt.Printf("housekeeper %s (%s, %s/%s)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return exitOK
+}
+
+// configFlags registers the config file flags on fs and returns a function
+// that finds and loads the config for the scanned dir once fs is parsed
+func configFlags(fs *flag.FlagSet, dir *string) func() (*purge.Config, purge.ConfigLocation, error) {
+	path := fs.String("config", "", "Config file, JSON, YAML or TOML by extension (default: $"+purge.ConfigEnv+", else "+purge.DirConfigName+
+		" in the scanned dir or its ancestors, else $XDG_CONFIG_HOME/housekeeper/config.json, else built-in defaults; .yaml, .yml or .toml also found)")
+	legacy := legacyConfigFlags(fs, "")
+	collisions := fs.String("rename-collisions", "", "When a rename target exists: skip, suffix, keep_newer or delete_identical (default: from config, else skip)")
+
+	return func() (*purge.Config, purge.ConfigLocation, error) {
+		opts := legacy()
+		opts.Path, opts.Dir = *path, *dir
+		loc, err := purge.FindConfig(opts)
+		if err != nil {
+			return nil, loc, err
+		}
+		cfg, err := purge.LoadConfig(opts)
+		if err != nil {
+			return nil, loc, err
+		}
+		if *collisions != "" {
+			if cfg.RenameCollisions, err = purge.ParseCollisionPolicy(*collisions); err != nil {
+				return nil, loc, err
+			}
+			cfg.Sources["rename_collisions"] = "-rename-collisions flag"
+		}
+		return cfg, loc, nil
+	}
+}
+
+// warnConfig logs the problems found with the config that do not keep it
+// from being used, once logging is set up
+func warnConfig(cfg *purge.Config) {
+	for _, w := range cfg.Warnings {
+		common.Warn.Printf("%s", w)
+	}
+}
+
+// legacyConfigFlags registers the flags for the files of the legacy config
+// layout, with paths under dir as defaults unless dir is empty. The
+// returned function gives the load options once fs is parsed.
+func legacyConfigFlags(fs *flag.FlagSet, dir string) func() purge.LoadConfigOptions {
+	defaultPath := func(elem ...string) string {
+		if dir == "" {
+			return ""
+		}
+		return filepath.Join(append([]string{dir}, elem...)...)
+	}
+	exts := fs.String("exts", defaultPath("userconfigs", "extensions_to_delete.json"), "Legacy delete config")
+	repls := fs.String("repls", defaultPath("userconfigs", "extension_replacements.json"), "Legacy replace config")
+	rules := fs.String("rules", defaultPath("userconfigs", "delete_rules.json"), "Legacy glob/regex delete rules config")
+	settings := fs.String("settings", defaultPath("config.json"), "Legacy general settings config (prefixes, hidden files)")
+
+	return func() purge.LoadConfigOptions {
+		return purge.LoadConfigOptions{
+			DeleteConfigPath:  *exts,
+			ReplaceConfigPath: *repls,
+			RulesConfigPath:   *rules,
+			SettingsPath:      *settings,
+		}
 	}
+}
+
+// applyFlags registers the apply flags on fs and returns a function that
+// builds the apply options once fs is parsed
+func applyFlags(fs *flag.FlagSet) func() (purge.ApplyOptions, error) {
+	deleteMode := fs.String("delete-mode", "hard", "How to delete files: hard, quarantine or trash")
+	quarantineDir := fs.String("quarantine-dir", "", "Quarantine directory (default: <data dir>/housekeeper/quarantine)")
+	journal := fs.String("journal", "", "Undo journal to write when applying (default: <data dir>/housekeeper/journals/<time>.jsonl)")
+	strict := fs.Bool("strict", false, "Apply nothing if any file changed since it was planned")
+	concurrency := fs.Int("concurrency", 1, "Number of changes to apply at once")
+
+	return func() (purge.ApplyOptions, error) {
+		mode, err := purge.ParseDeleteMode(*deleteMode)
+		if err != nil {
+			return purge.ApplyOptions{}, err
+		}
+		return purge.ApplyOptions{
+			DeleteMode:    mode,
+			QuarantineDir: *quarantineDir,
+			JournalPath:   *journal,
+			Strict:        *strict,
+			Concurrency:   *concurrency,
+		}, nil
+	}
+}
+
+// outputFlags registers the output flags on fs and returns a function that
+// creates the output to w once fs is parsed
+func outputFlags(fs *flag.FlagSet) func(w io.Writer) (*output, error) {
+	format := fs.String("format", string(formatTable), "Output format: table, json, ndjson or csv")
 
-	fmt.Printf("\nApplied %d changes:\n", len(applied))
-	for i, change := range applied {
-		fmt.Printf("%2d. [APPLIED] %s\n", i+1, describeChange(change))
+	return func(w io.Writer) (*output, error) {
+		f, err := parseOutputFormat(*format)
+		if err != nil {
+			return nil, err
+		}
+		return newOutput(f, w), nil
 	}
 }
 
-func printChange(i int, change purge.Change) {
+// consoleLogging keeps console logs off stdout when out is for scripts
+func consoleLogging(cfg common.LoggingConfig, out *output) common.LoggingConfig {
+	if out.machine() {
+		cfg.Console = os.Stderr
+	}
+	return cfg
+}
+
+// loggingFlags registers the logging flags on fs and returns a function
+// that builds the logging config once fs is parsed. Flags given on the
+// command line win over the settings of the config file.
+func loggingFlags(fs *flag.FlagSet) func(common.LoggingSettings) common.LoggingConfig {
+	logToFile := fs.Bool("log-to-file", true, "Enable file-based logging")
+	logPath := fs.String("log-path", "logs/toolkit.log", "Path to log file")
+	debugLogs := fs.Bool("debug", false, "Enable debug-level logs")
+	alsoPrint := fs.Bool("also-print-to-console", true, "Also print logs to console when logging to file")
+
+	return func(settings common.LoggingSettings) common.LoggingConfig {
+		cfg := settings.Apply(common.LoggingConfig{
+			LogToFile:          *logToFile,
+			LogFilePath:        *logPath,
+			Debug:              *debugLogs,
+			AlsoPrintToConsole: *alsoPrint, // if logging to file, default no; or set manually
+		})
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "log-to-file":
+				cfg.LogToFile = *logToFile
+			case "log-path":
+				cfg.LogFilePath = *logPath
+			case "debug":
+				cfg.Debug = *debugLogs
+			case "also-print-to-console":
+				cfg.AlsoPrintToConsole = *alsoPrint
+			}
+		})
+		return cfg
+	}
+}
+
+func printChange(w io.Writer, i int, change purge.Change) {
 	switch change.Type {
 	case purge.DeleteFile:
-		fmt.Printf("%2d. [DELETE]     %s\n", i, change.Target)
+		fmt.Fprintf(w, "%2d. [DELETE]     %s\n", i, change.Target)
 	case purge.RenameFile:
-		fmt.Printf("%2d. [RENAME]     %s → %s\n", i, change.Target, change.NewName)
+		fmt.Fprintf(w, "%2d. [RENAME]     %s → %s\n", i, change.Target, change.NewName)
 	case purge.RemoveDir:
-		fmt.Printf("%2d. [REMOVE DIR] %s\n", i, change.Target)
+		fmt.Fprintf(w, "%2d. [REMOVE DIR] %s\n", i, change.Target)
 	default:
-		fmt.Printf("%2d. [UNKNOWN]    %s (%s)\n", i, change.Target, change.Type)
+		fmt.Fprintf(w, "%2d. [UNKNOWN]    %s (%s)\n", i, change.Target, change.Type)
+	}
+	if change.Reason != nil {
+		fmt.Fprintf(w, "                 ↳ %s\n", change.Reason)
+	}
+	if c := change.Collision; c != nil {
+		fmt.Fprintf(w, "                 ↳ collides with %s: %s (%s)\n", c.With, c.Outcome, c.Policy)
 	}
 }
 
 func describeChange(change purge.Change) string {
 	switch change.Type {
 	case purge.DeleteFile:
-		return fmt.Sprintf("Deleted %s", change.Target)
+		return fmt.Sprintf("Delete %s", change.Target)
 	case purge.RenameFile:
-		return fmt.Sprintf("Renamed %s → %s", change.Target, change.NewName)
+		return fmt.Sprintf("Rename %s → %s", change.Target, change.NewName)
 	case purge.RemoveDir:
-		return fmt.Sprintf("Removed empty dir %s", change.Target)
+		return fmt.Sprintf("Remove empty dir %s", change.Target)
 	default:
 		return fmt.Sprintf("Unknown action on %s", change.Target)
 	}
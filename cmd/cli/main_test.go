@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"housekeeper/internal/jobs/purge"
+)
+
+// quietCLI discards what commands print, and keeps config discovery and
+// the working dir from leaking out of the test
+func quietCLI(t *testing.T) {
+	t.Helper()
+	t.Setenv(purge.ConfigEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+		os.Chdir(wd)
+	})
+}
+
+func TestRunExitCodes(t *testing.T) {
+	const noLogs = "-log-to-file=false"
+	tests := []struct {
+		name  string
+		files map[string]string // in the working dir
+		args  []string
+		want  int
+	}{
+		{name: "no command", args: nil, want: exitError},
+		{name: "help", args: []string{"help"}, want: exitOK},
+		{name: "unknown command", args: []string{"clean"}, want: exitError},
+		{name: "command help", args: []string{"plan", "-h"}, want: exitOK},
+		{name: "bad flag", args: []string{"plan", "-nope"}, want: exitError},
+		{name: "bad flag value", args: []string{"plan", "-workers", "many"}, want: exitError},
+		{name: "extra argument", args: []string{"plan", noLogs, "dir"}, want: exitError},
+		{
+			name:  "plan without changes",
+			files: map[string]string{"keep.txt": "x"},
+			args:  []string{"plan", noLogs},
+			want:  exitOK,
+		},
+		{
+			name:  "plan with changes",
+			files: map[string]string{"keep.txt": "x", "a.tmp": "x"},
+			args:  []string{"plan", noLogs},
+			want:  exitChanges,
+		},
+		{
+			name:  "plan with an invalid config",
+			files: map[string]string{purge.DirConfigName: `{"version": 1, "delete": {"rules": [{"kind": "regex", "pattern": "("}]}}`},
+			args:  []string{"plan", noLogs},
+			want:  exitError,
+		},
+		{
+			name:  "legacy flags plan",
+			files: map[string]string{"keep.txt": "x", "a.tmp": "x"},
+			args:  []string{"-dir", ".", noLogs},
+			want:  exitChanges,
+		},
+		{
+			name:  "legacy dir named apply",
+			files: map[string]string{"apply/keep.txt": "x", "apply/a.tmp": "x"},
+			args:  []string{"-dir", "apply", noLogs},
+			want:  exitChanges,
+		},
+		{
+			name:  "config validate",
+			files: map[string]string{purge.DirConfigName: `{"version": 1}`},
+			args:  []string{"config", "validate"},
+			want:  exitOK,
+		},
+		{
+			name:  "config validate with warnings",
+			files: map[string]string{purge.DirConfigName: `{"version": 1, "rename": {"extension_replacements": {"add": {"jpeg": ".jpg"}}}}`},
+			args:  []string{"config", "validate"},
+			want:  exitChanges,
+		},
+		{
+			name:  "config validate with errors",
+			files: map[string]string{purge.DirConfigName: `{"version": 1, "rename": {"extension_replacements": {"add": {".a": ".b", ".b": ".a"}}}}`},
+			args:  []string{"config", "validate", "-json"},
+			want:  exitError,
+		},
+		{name: "unknown config command", args: []string{"config", "check"}, want: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quietCLI(t)
+			dir := t.TempDir()
+			for name, data := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+			}
+			require.NoError(t, os.Chdir(dir))
+
+			assert.Equal(t, tt.want, run(context.Background(), tt.args))
+			for name := range tt.files {
+				assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(name)), "nothing is applied")
+			}
+		})
+	}
+}
+
+func TestApplyPlanConfigChanged(t *testing.T) {
+	const noLogs = "-log-to-file=false"
+	quietCLI(t)
+	dir := t.TempDir()
+	require.NoError(t, os.Chdir(dir))
+	write := func(name, data string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+	write(purge.DirConfigName, `{"version": 1, "delete": {"extensions": [".tmp"]}}`)
+	write("a.tmp", "x")
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	require.Equal(t, exitChanges, run(context.Background(), []string{"plan", noLogs, "-hash", "-o", planPath}))
+
+	write(purge.DirConfigName, `{"version": 1, "delete": {"extensions": [".tmp", ".bak"]}}`)
+	assert.Equal(t, exitError, run(context.Background(), []string{"apply", noLogs, "-strict", planPath}))
+	assert.FileExists(t, filepath.Join(dir, "a.tmp"), "nothing is applied under -strict")
+
+	assert.Equal(t, exitOK, run(context.Background(), []string{"apply", noLogs, planPath}))
+	assert.NoFileExists(t, filepath.Join(dir, "a.tmp"), "a changed config only warns")
+}
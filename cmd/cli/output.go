@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"housekeeper/internal/jobs/purge"
+)
+
+// outputFormat selects how changes and apply results are written to stdout
+type outputFormat string
+
+const (
+	formatTable  outputFormat = "table"  // numbered listing for people
+	formatJSON   outputFormat = "json"   // one document with all changes and results
+	formatNDJSON outputFormat = "ndjson" // one record per line, as they come
+	formatCSV    outputFormat = "csv"    // one record per row, with a header
+)
+
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch f := outputFormat(s); f {
+	case formatTable, formatJSON, formatNDJSON, formatCSV:
+		return f, nil
+	case "":
+		return formatTable, nil
+	}
+	return "", fmt.Errorf("invalid output format %q (want table, json, ndjson or csv)", s)
+}
+
+// record is a planned change or an apply result in machine-readable
+// output. Scripts depend on the field names, so they must not change; every
+// field is always present.
+type record struct {
+	Record           string  `json:"record"` // "change" or "result"
+	Index            int     `json:"index"`  // 1-based position in the listing
+	Type             string  `json:"type"`
+	Target           string  `json:"target"`
+	NewName          string  `json:"new_name"`
+	RuleID           string  `json:"rule_id"`
+	Reason           string  `json:"reason"`
+	CollisionWith    string  `json:"collision_with"`
+	CollisionPolicy  string  `json:"collision_policy"`
+	CollisionOutcome string  `json:"collision_outcome"`
+	Status           string  `json:"status"` // results only
+	Error            string  `json:"error"`
+	DurationMS       float64 `json:"duration_ms"`
+	BytesFreed       int64   `json:"bytes_freed"`
+}
+
+var csvHeader = []string{
+	"record", "index", "type", "target", "new_name", "rule_id", "reason",
+	"collision_with", "collision_policy", "collision_outcome",
+	"status", "error", "duration_ms", "bytes_freed",
+}
+
+func (r record) csvRow() []string {
+	return []string{
+		r.Record, strconv.Itoa(r.Index), r.Type, r.Target, r.NewName, r.RuleID, r.Reason,
+		r.CollisionWith, r.CollisionPolicy, r.CollisionOutcome,
+		r.Status, r.Error, strconv.FormatFloat(r.DurationMS, 'f', -1, 64), strconv.FormatInt(r.BytesFreed, 10),
+	}
+}
+
+func changeRecord(i int, c purge.Change) record {
+	r := record{Record: "change", Index: i, Type: string(c.Type), Target: c.Target, NewName: c.NewName}
+	if c.Reason != nil {
+		r.RuleID, r.Reason = c.Reason.RuleID, c.Reason.String()
+	}
+	if c.Collision != nil {
+		r.CollisionWith = c.Collision.With
+		r.CollisionPolicy = string(c.Collision.Policy)
+		r.CollisionOutcome = string(c.Collision.Outcome)
+	}
+	return r
+}
+
+func resultRecord(i int, res purge.ApplyResult) record {
+	r := changeRecord(i, res.Change)
+	r.Record = "result"
+	r.Status = string(res.Status)
+	if res.Err != nil {
+		r.Error = res.Err.Error()
+	}
+	r.DurationMS = float64(res.Duration) / float64(time.Millisecond)
+	r.BytesFreed = res.BytesFreed
+	return r
+}
+
+// output writes planned changes and apply results to stdout in a format.
+// Notes for people go to stdout with the table format and to stderr
+// otherwise, so machine-readable output stays clean.
+type output struct {
+	format  outputFormat
+	w       io.Writer
+	csv     *csv.Writer
+	results bool  // whether a result was written yet
+	err     error // first write error, returned by close
+
+	// Buffered for the json format, written by close
+	doc struct {
+		Changes []record `json:"changes"`
+		Results []record `json:"results"`
+	}
+}
+
+func newOutput(format outputFormat, w io.Writer) *output {
+	o := &output{format: format, w: w}
+	o.doc.Changes, o.doc.Results = []record{}, []record{}
+	if format == formatCSV {
+		o.csv = csv.NewWriter(w)
+		o.err = o.csv.Write(csvHeader)
+	}
+	return o
+}
+
+// machine reports whether the output is meant for scripts
+func (o *output) machine() bool {
+	return o.format != formatTable
+}
+
+func (o *output) change(i int, c purge.Change) {
+	if o.format == formatTable {
+		printChange(o.w, i, c)
+		return
+	}
+	o.write(changeRecord(i, c))
+}
+
+func (o *output) result(i int, r purge.ApplyResult) {
+	if o.format == formatTable {
+		if !o.results {
+			fmt.Fprintln(o.w, "\nResults:")
+		}
+		printResult(o.w, i, r)
+	} else {
+		o.write(resultRecord(i, r))
+	}
+	o.results = true
+}
+
+func (o *output) write(r record) {
+	switch o.format {
+	case formatJSON:
+		if r.Record == "result" {
+			o.doc.Results = append(o.doc.Results, r)
+		} else {
+			o.doc.Changes = append(o.doc.Changes, r)
+		}
+	case formatNDJSON:
+		o.fail(json.NewEncoder(o.w).Encode(r))
+	case formatCSV:
+		o.fail(o.csv.Write(r.csvRow()))
+	}
+}
+
+// fail keeps err if it is the first write error
+func (o *output) fail(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+// notef prints a note for people
+func (o *output) notef(format string, args ...any) {
+	w := o.w
+	if o.machine() {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+// close finishes the output and returns the first error writing it;
+// nothing may be written after it
+func (o *output) close() error {
+	switch o.format {
+	case formatJSON:
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		o.fail(enc.Encode(o.doc))
+	case formatCSV:
+		o.csv.Flush()
+		o.fail(o.csv.Error())
+	}
+	return o.err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"housekeeper/internal/jobs/purge"
+)
+
+// A planned change with every field set, one with none, and the results of
+// applying them
+var (
+	renameChange = purge.Change{
+		Type:      purge.RenameFile,
+		Target:    "/scan/a.JPEG",
+		NewName:   "/scan/a-1.jpg",
+		Reason:    &purge.Reason{RuleID: "ext:.jpeg", Kind: purge.RuleSuffix, Pattern: ".jpeg", Source: "/cfg.json"},
+		Collision: &purge.Collision{With: "/scan/a.jpg", Policy: purge.CollisionSuffix, Outcome: purge.OutcomeSuffixed},
+	}
+	deleteChange  = purge.Change{Type: purge.DeleteFile, Target: `/scan/b,"x".tmp`}
+	outputResults = []purge.ApplyResult{
+		{Change: renameChange, Status: purge.StatusApplied, Duration: 2 * time.Millisecond, BytesFreed: 4},
+		{Change: deleteChange, Status: purge.StatusFailed, Err: errors.New("permission denied"), Duration: 1500 * time.Microsecond},
+	}
+)
+
+func TestOutputFormats(t *testing.T) {
+	const changeA = `"record":"change","index":1,"type":"rename_file","target":"/scan/a.JPEG","new_name":"/scan/a-1.jpg",` +
+		`"rule_id":"ext:.jpeg","reason":"suffix \".jpeg\" (rule ext:.jpeg from /cfg.json)",` +
+		`"collision_with":"/scan/a.jpg","collision_policy":"suffix","collision_outcome":"suffixed",` +
+		`"status":"","error":"","duration_ms":0,"bytes_freed":0`
+	const changeB = `"record":"change","index":2,"type":"delete_file","target":"/scan/b,\"x\".tmp","new_name":"",` +
+		`"rule_id":"","reason":"","collision_with":"","collision_policy":"","collision_outcome":"",` +
+		`"status":"","error":"","duration_ms":0,"bytes_freed":0`
+	const resultA = `"record":"result","index":1,"type":"rename_file","target":"/scan/a.JPEG","new_name":"/scan/a-1.jpg",` +
+		`"rule_id":"ext:.jpeg","reason":"suffix \".jpeg\" (rule ext:.jpeg from /cfg.json)",` +
+		`"collision_with":"/scan/a.jpg","collision_policy":"suffix","collision_outcome":"suffixed",` +
+		`"status":"applied","error":"","duration_ms":2,"bytes_freed":4`
+	const resultB = `"record":"result","index":2,"type":"delete_file","target":"/scan/b,\"x\".tmp","new_name":"",` +
+		`"rule_id":"","reason":"","collision_with":"","collision_policy":"","collision_outcome":"",` +
+		`"status":"failed","error":"permission denied","duration_ms":1.5,"bytes_freed":0`
+
+	tests := []struct {
+		format outputFormat
+		want   string
+	}{
+		{
+			format: formatTable,
+			want: ` 1. [RENAME]     /scan/a.JPEG → /scan/a-1.jpg
+                 ↳ suffix ".jpeg" (rule ext:.jpeg from /cfg.json)
+                 ↳ collides with /scan/a.jpg: suffixed (suffix)
+ 2. [DELETE]     /scan/b,"x".tmp
+
+Results:
+ 1. [APPLIED] Rename /scan/a.JPEG → /scan/a-1.jpg (4 B freed)
+ 2. [FAILED] Delete /scan/b,"x".tmp
+                 ↳ permission denied
+`,
+		},
+		{
+			format: formatNDJSON,
+			want:   "{" + changeA + "}\n{" + changeB + "}\n{" + resultA + "}\n{" + resultB + "}\n",
+		},
+		{
+			format: formatCSV,
+			want: `record,index,type,target,new_name,rule_id,reason,collision_with,collision_policy,collision_outcome,status,error,duration_ms,bytes_freed
+change,1,rename_file,/scan/a.JPEG,/scan/a-1.jpg,ext:.jpeg,"suffix "".jpeg"" (rule ext:.jpeg from /cfg.json)",/scan/a.jpg,suffix,suffixed,,,0,0
+change,2,delete_file,"/scan/b,""x"".tmp",,,,,,,,,0,0
+result,1,rename_file,/scan/a.JPEG,/scan/a-1.jpg,ext:.jpeg,"suffix "".jpeg"" (rule ext:.jpeg from /cfg.json)",/scan/a.jpg,suffix,suffixed,applied,,2,4
+result,2,delete_file,"/scan/b,""x"".tmp",,,,,,,failed,permission denied,1.5,0
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			writeOutputRecords(t, newOutput(tt.format, &b))
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+
+	// The json document holds the same records, in two lists
+	t.Run(string(formatJSON), func(t *testing.T) {
+		var b bytes.Buffer
+		writeOutputRecords(t, newOutput(formatJSON, &b))
+		var compact bytes.Buffer
+		require.NoError(t, json.Compact(&compact, b.Bytes()))
+		assert.Equal(t, `{"changes":[{`+changeA+`},{`+changeB+`}],"results":[{`+resultA+`},{`+resultB+`}]}`, compact.String())
+		assert.Contains(t, b.String(), "\n  \"changes\": [\n    {\n      \"record\": \"change\",\n", "the document is indented")
+	})
+}
+
+func writeOutputRecords(t *testing.T, o *output) {
+	t.Helper()
+	o.change(1, renameChange)
+	o.change(2, deleteChange)
+	for i, r := range outputResults {
+		o.result(i+1, r)
+	}
+	require.NoError(t, o.close())
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestOutputWriteErrors(t *testing.T) {
+	for _, format := range []outputFormat{formatJSON, formatNDJSON, formatCSV} {
+		o := newOutput(format, failingWriter{})
+		o.change(1, renameChange)
+		o.result(1, outputResults[0])
+		assert.ErrorContains(t, o.close(), "broken pipe", format)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"housekeeper/internal/jobs/purge"
+)
+
+// progressInterval limits how often the progress line is redrawn
+const progressInterval = 100 * time.Millisecond
+
+// progressLine keeps a one-line summary of a plan or an apply up to date. A
+// nil *progressLine draws nothing.
+type progressLine struct {
+	mu     sync.Mutex // events arrive while the listing is printed
+	out    io.Writer
+	total  int // changes being applied, 0 when planning
+	totals purge.ProgressTotals
+	last   time.Time
+	width  int // of the line drawn last, 0 if none is pending
+}
+
+// newProgressLine returns a progress line on stderr for a plan (total 0) or
+// for applying total changes. It is nil unless stderr is a terminal.
+func newProgressLine(total int) *progressLine {
+	info, err := os.Stderr.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return &progressLine{out: os.Stderr, total: total}
+}
+
+// observer returns p as a purge.ProgressObserver, or nil if p is nil
+func (p *progressLine) observer() purge.ProgressObserver {
+	if p == nil {
+		return nil
+	}
+	return p
+}
+
+func (p *progressLine) Progress(e purge.ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.totals = e.Totals
+	if time.Since(p.last) >= progressInterval {
+		p.draw()
+	}
+}
+
+func (p *progressLine) draw() {
+	t := p.totals
+	line := fmt.Sprintf("Scanned %d dirs, %d files, found %d changes", t.DirsScanned, t.FilesScanned, t.ChangesFound)
+	if p.total > 0 {
+		line = fmt.Sprintf("Applied %d/%d changes, %d failed, %s reclaimed",
+			t.ChangesApplied, p.total, t.ChangesFailed, formatBytes(t.BytesReclaimed))
+	}
+	fmt.Fprintf(p.out, "\r%-*s", p.width, line)
+	p.width = len(line)
+	p.last = time.Now()
+}
+
+// clear erases the line, so a listing printed next does not run into it. It
+// is drawn again with the next event due.
+func (p *progressLine) clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.width > 0 {
+		fmt.Fprintf(p.out, "\r%s\r", strings.Repeat(" ", p.width))
+		p.width = 0
+	}
+}
+
+// finish draws the final totals and ends the line, so later output starts
+// on a new line
+func (p *progressLine) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.width > 0 || p.total == 0 {
+		p.draw()
+		fmt.Fprintln(p.out)
+		p.width = 0
+	}
+}
+
+// formatBytes formats n with a binary unit, e.g. "1.5 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -2,14 +2,32 @@ package main
 
 import (
     "context"
+    "errors"
     "housekeeper/internal/common"
     "housekeeper/internal/jobs/purge"
+    "sync"
+    "time"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// ProgressEventName is the runtime event carrying job progress to the frontend
+const ProgressEventName = "purge:progress"
+
+// progressInterval limits how often progress events are emitted
+const progressInterval = 100 * time.Millisecond
+
+// eventsEmit sends runtime events to the frontend; tests replace it
+var eventsEmit = runtime.EventsEmit
+
 // App struct
 type App struct {
-    ctx context.Context
+    ctx        context.Context
+    cancel     context.CancelFunc // stops running jobs on shutdown
+    deleteMode purge.DeleteMode
+
+    mu        sync.Mutex
+    planned   map[string]purge.Change // last preview by changeKey, for ApplyChanges
+    plannedBy *purge.Config           // config of the last preview, whose safety limits apply
 }
 
 // NewApp creates a new App application struct
@@ -20,7 +38,7 @@ func NewApp() *App {
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
-    a.ctx = ctx
+    a.ctx, a.cancel = context.WithCancel(ctx)
     // Set up logging (same as CLI)
     common.SetupLogging(common.LoggingConfig{
         LogToFile:          true,
@@ -30,38 +48,128 @@ func (a *App) startup(ctx context.Context) {
     })
 }
 
-// GetChanges runs the purge job and returns the list of changes
-func (a *App) GetChanges(dir string, deleteConfigPath string, replaceConfigPath string) ([]Change, error) {
+// shutdown is called when the app closes and stops any running job
+func (a *App) shutdown(ctx context.Context) {
+    if a.cancel != nil {
+        a.cancel()
+    }
+}
+
+// GetChanges runs the purge job and returns the list of changes. An empty
+// configPath uses the config found for dir, see purge.FindConfig.
+func (a *App) GetChanges(dir string, configPath string) ([]Change, error) {
     // Load configuration
-    cfg, err := purge.LoadConfigWithOptions(purge.LoadConfigOptions{
-        DeleteConfigPath:  deleteConfigPath,
-        ReplaceConfigPath: replaceConfigPath,
-    })
+    cfg, err := purge.LoadConfig(purge.LoadConfigOptions{Path: configPath, Dir: dir})
     if err != nil {
         return nil, err
     }
 
     // Create and run the purge job
     job := purge.NewJob(dir, cfg)
-    changes, err := job.Plan()
+    job.Progress = &progressEmitter{ctx: a.ctx}
+    changes, err := job.Plan(a.ctx)
     if err != nil {
         return nil, err
     }
 
     // Convert purge.Change to a JSON-serializable struct
     result := make([]Change, len(changes))
+    planned := make(map[string]purge.Change, len(changes))
     for i, change := range changes {
-        result[i] = Change{
-            Type:     string(change.Type),
-            Target:   change.Target,
-            NewName:  change.NewName,
-            Selected: true, // Default: checked
-        }
+        result[i] = newChange(change)
+        planned[result[i].key()] = change
     }
 
+    a.mu.Lock()
+    a.planned, a.plannedBy = planned, cfg
+    a.mu.Unlock()
+
     return result, nil
 }
 
+// ApplyChanges applies the selected changes of the last preview and reports
+// the outcome of each. Changes are looked up in that preview and checked
+// against their fingerprints first, so files changed since are left alone,
+// and the safety limits of the preview's config apply. Applied changes are
+// journaled and can be undone.
+func (a *App) ApplyChanges(changes []Change) (*ApplyReport, error) {
+    a.mu.Lock()
+    planned, cfg := a.planned, a.plannedBy
+    a.mu.Unlock()
+
+    var toApply []purge.Change
+    report := &ApplyReport{}
+    index := make(map[string]int) // changeKey → position in report.Results
+    for _, c := range changes {
+        if !c.Selected {
+            continue
+        }
+        change, ok := planned[c.key()]
+        if !ok {
+            report.Results = append(report.Results, ChangeResult{Change: c, Status: StatusFailed, Error: "not part of the last preview"})
+            continue
+        }
+        status := StatusPending
+        if change.Skipped() {
+            status = StatusSkipped
+        }
+        index[c.key()] = len(report.Results)
+        report.Results = append(report.Results, ChangeResult{Change: c, Status: status})
+        toApply = append(toApply, change)
+    }
+    if len(toApply) == 0 {
+        return report, nil
+    }
+
+    journal, err := purge.DefaultJournalPath(time.Now())
+    if err != nil {
+        return nil, err
+    }
+
+    results, err := purge.ApplyAllContext(a.ctx, toApply, purge.ApplyOptions{
+        DeleteMode:  a.deleteMode,
+        JournalPath: journal,
+        Limits:      cfg.Safety,
+        Progress:    &progressEmitter{ctx: a.ctx},
+    })
+    if results == nil && err != nil {
+        return nil, err // nothing was attempted
+    }
+
+    for _, r := range results {
+        i, ok := index[newChange(r.Change).key()]
+        if !ok {
+            common.Warn.Printf("Apply reported %s, which was not selected", r.Change.Target)
+            continue
+        }
+        result := &report.Results[i]
+        result.Status, result.BytesFreed = string(r.Status), r.BytesFreed
+        if r.Err != nil {
+            result.Error = r.Err.Error()
+        }
+        var stale *purge.StaleError
+        if errors.As(r.Err, &stale) {
+            result.Status = StatusStale
+        }
+        if r.Status == purge.StatusApplied {
+            report.JournalPath = journal
+        }
+    }
+
+    return report, nil
+}
+
+// SetDeleteMode selects how files are deleted when changes are applied:
+// "hard", "quarantine" or "trash"
+func (a *App) SetDeleteMode(mode string) error {
+    parsed, err := purge.ParseDeleteMode(mode)
+    if err != nil {
+        return err
+    }
+    a.deleteMode = parsed
+    return nil
+}
+
 // OpenDirectoryDialog opens a directory selection dialog
 func (a *App) OpenDirectoryDialog(title string, defaultDirectory string) (string, error) {
 	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
@@ -72,8 +180,119 @@ func (a *App) OpenDirectoryDialog(title string, defaultDirectory string) (string
 
 // Change struct for JSON serialization
 type Change struct {
-    Type     string `json:"type"`
-    Target   string `json:"target"`
-    NewName  string `json:"newName"`
-    Selected bool   `json:"selected"`
+    Type      string     `json:"type"`
+    Target    string     `json:"target"`
+    NewName   string     `json:"newName"`
+    Reason    *Reason    `json:"reason,omitempty"`
+    Collision *Collision `json:"collision,omitempty"`
+    Selected  bool       `json:"selected"`
+}
+
+func newChange(c purge.Change) Change {
+    return Change{
+        Type:      string(c.Type),
+        Target:    c.Target,
+        NewName:   c.NewName,
+        Reason:    newReason(c.Reason),
+        Collision: newCollision(c.Collision),
+        Selected:  !c.Skipped(), // Default: checked unless a collision skipped it
+    }
+}
+
+// key identifies the change within a preview
+func (c Change) key() string {
+    return c.Type + "\x00" + c.Target + "\x00" + c.NewName
+}
+
+// Change statuses reported by ApplyChanges, on top of those of purge.ApplyStatus
+const (
+    StatusFailed  = string(purge.StatusFailed)
+    StatusSkipped = string(purge.StatusSkipped)
+    StatusPending = string(purge.StatusPending)
+    StatusStale   = "stale" // the file changed since the preview and was left alone
+)
+
+// ChangeResult is the outcome of one change passed to ApplyChanges
+type ChangeResult struct {
+    Change     Change `json:"change"`
+    Status     string `json:"status"`
+    Error      string `json:"error,omitempty"`
+    BytesFreed int64  `json:"bytesFreed"`
+}
+
+// ApplyReport is the outcome of ApplyChanges
+type ApplyReport struct {
+    Results     []ChangeResult `json:"results"`
+    JournalPath string         `json:"journalPath,omitempty"` // undo journal, if anything was applied
+}
+
+// Reason explains which rule planned a change
+type Reason struct {
+    RuleID      string `json:"ruleId"`
+    Kind        string `json:"kind"`
+    Pattern     string `json:"pattern"`
+    Conditions  string `json:"conditions"`
+    Source      string `json:"source"`
+    Description string `json:"description"`
+}
+
+func newReason(r *purge.Reason) *Reason {
+    if r == nil {
+        return nil
+    }
+    return &Reason{
+        RuleID:      r.RuleID,
+        Kind:        string(r.Kind),
+        Pattern:     r.Pattern,
+        Conditions:  r.Conditions,
+        Source:      r.Source,
+        Description: r.String(),
+    }
+}
+
+// Collision explains how a clashing rename was resolved
+type Collision struct {
+    With    string `json:"with"`
+    Policy  string `json:"policy"`
+    Outcome string `json:"outcome"`
+}
+
+func newCollision(c *purge.Collision) *Collision {
+    if c == nil {
+        return nil
+    }
+    return &Collision{
+        With:    c.With,
+        Policy:  string(c.Policy),
+        Outcome: string(c.Outcome),
+    }
+}
+
+// Progress is the payload of ProgressEventName events
+type Progress struct {
+    Kind   string               `json:"kind"`
+    Path   string               `json:"path"`
+    Error  string               `json:"error,omitempty"`
+    Totals purge.ProgressTotals `json:"totals"`
+}
+
+// progressEmitter forwards job progress to the frontend. Events are
+// throttled, except the ones ending a plan or reporting a failure.
+type progressEmitter struct {
+    ctx  context.Context
+    last time.Time
+}
+
+func (p *progressEmitter) Progress(e purge.ProgressEvent) {
+    important := e.Kind == purge.ProgressPlanned || e.Kind == purge.ProgressChangeFailed
+    if !important && time.Since(p.last) < progressInterval {
+        return
+    }
+    p.last = time.Now()
+
+    progress := Progress{Kind: string(e.Kind), Path: e.Path, Totals: e.Totals}
+    if e.Err != nil {
+        progress.Error = e.Err.Error()
+    }
+    eventsEmit(p.ctx, ProgressEventName, progress)
 }
\ No newline at end of file
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"housekeeper/internal/jobs/purge"
+)
+
+// newTestApp returns an App whose jobs run on a fresh in-memory filesystem
+// holding files, with the config in configJSON
+func newTestApp(t *testing.T, files map[string]string, configJSON string) (*App, afero.Fs, string) {
+	t.Helper()
+	t.Setenv(purge.ConfigEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	fsys := afero.NewMemMapFs()
+	originalFs, originalUnlock, originalEmit := purge.AppFs, purge.UnlockPath, eventsEmit
+	purge.AppFs = fsys
+	purge.UnlockPath = func(afero.Fs, string) error { return nil }
+	eventsEmit = func(context.Context, string, ...interface{}) {}
+	t.Cleanup(func() {
+		purge.AppFs, purge.UnlockPath, eventsEmit = originalFs, originalUnlock, originalEmit
+	})
+
+	for name, data := range files {
+		require.NoError(t, afero.WriteFile(fsys, name, []byte(data), 0644))
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(configJSON), 0644))
+	return &App{ctx: context.Background()}, fsys, configPath
+}
+
+func TestApplyChanges(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "scan")
+	p := func(name string) string { return filepath.Join(root, name) }
+	app, fsys, configPath := newTestApp(t, map[string]string{
+		p("a.tmp"):  "junk",
+		p("b.tmp"):  "junk",
+		p("c.JPEG"): "img",
+		p("d.tmp"):  "junk",
+	}, `{"version": 1, "delete": {"extensions": [".tmp"]}, "rename": {"extension_replacements": {".jpeg": ".jpg"}}}`)
+
+	changes, err := app.GetChanges(root, configPath)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	// b.tmp changes after the preview, d.tmp is left unselected, and the
+	// frontend sends a change the preview never had
+	require.NoError(t, afero.WriteFile(fsys, p("b.tmp"), []byte("edited since"), 0644))
+	for i := range changes {
+		if changes[i].Target == p("d.tmp") {
+			changes[i].Selected = false
+		}
+	}
+	unknown := Change{Type: string(purge.DeleteFile), Target: p("e.tmp"), Selected: true}
+	changes = append(changes, unknown)
+
+	report, err := app.ApplyChanges(changes)
+	require.NoError(t, err)
+	got := make(map[string]ChangeResult)
+	for _, r := range report.Results {
+		got[r.Change.Target] = r
+	}
+	assert.Len(t, got, 4, "unselected changes are left out")
+	assert.Equal(t, string(purge.StatusApplied), got[p("a.tmp")].Status)
+	assert.Equal(t, int64(4), got[p("a.tmp")].BytesFreed)
+	assert.Equal(t, StatusStale, got[p("b.tmp")].Status)
+	assert.NotEmpty(t, got[p("b.tmp")].Error)
+	assert.Equal(t, string(purge.StatusApplied), got[p("c.JPEG")].Status)
+	assert.Equal(t, StatusFailed, got[p("e.tmp")].Status)
+	assert.Equal(t, "not part of the last preview", got[p("e.tmp")].Error)
+	assert.NotEmpty(t, report.JournalPath)
+
+	for name, want := range map[string]bool{"a.tmp": false, "b.tmp": true, "c.jpg": true, "d.tmp": true} {
+		exists, err := afero.Exists(fsys, p(name))
+		require.NoError(t, err)
+		assert.Equal(t, want, exists, name)
+	}
+}
+
+func TestApplyChangesSafetyLimits(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "scan")
+	app, fsys, configPath := newTestApp(t, map[string]string{
+		filepath.Join(root, "a.tmp"):    "junk",
+		filepath.Join(root, "b.tmp"):    "junk",
+		filepath.Join(root, "keep.txt"): "x",
+	}, `{"version": 1, "delete": {"extensions": [".tmp"]}, "safety": {"max_changes": 1}}`)
+
+	changes, err := app.GetChanges(root, configPath)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	_, err = app.ApplyChanges(changes)
+	assert.ErrorContains(t, err, "safety limit")
+	exists, _ := afero.Exists(fsys, filepath.Join(root, "a.tmp"))
+	assert.True(t, exists, "nothing is applied over the limit")
+}
@@ -19,9 +19,24 @@ window.onload = () => {
   const selectedPathSpan = document.getElementById("selected-path");
   const changesList = document.getElementById("changes-list");
   const logOutput = document.getElementById("log-output");
+  const deleteModeSelect = document.getElementById("delete-mode");
+  const applyButton = document.getElementById("apply-changes");
+  let currentChanges = [];
 
   changesList.innerHTML = DEFAULT_CHANGES_MSG;
 
+  window.runtime?.EventsOn?.("purge:progress", (progress) => {
+    logOutput && (logOutput.textContent = describeProgress(progress));
+  });
+
+  deleteModeSelect?.addEventListener("change", async () => {
+    try {
+      await window.go.main.App.SetDeleteMode(deleteModeSelect.value);
+    } catch (error) {
+      logOutput && (logOutput.textContent = `Invalid delete mode: ${error}`);
+    }
+  });
+
   selectFolderButton?.addEventListener("click", async () => {
     try {
       logOutput && (logOutput.textContent = "Opening folder picker...");
@@ -32,6 +47,8 @@ window.onload = () => {
       if (!folderPath) {
         selectedPathSpan.textContent = NO_FOLDER_SELECTED;
         changesList.innerHTML = DEFAULT_CHANGES_MSG;
+        currentChanges = [];
+        applyButton && (applyButton.disabled = true);
         return;
       }
 
@@ -39,21 +56,59 @@ window.onload = () => {
 
       const changes = await window.go.main.App.GetChanges(
         folderPath,
-        "../userconfigs/extensions_to_delete.json",
-        "../userconfigs/extension_replacements.json"
+        ""
       );
 
-      renderChanges(changesList, changes);
+      currentChanges = changes || [];
+      renderChanges(changesList, currentChanges);
+      applyButton && (applyButton.disabled = currentChanges.length === 0);
     } catch (error) {
       console.error("Error fetching changes:", error);
       changesList.innerHTML = `<p class='result'>Error loading changes: ${error}</p>`;
       window.runtime?.LogError?.("Error fetching changes: " + error);
     }
   });
+
+  applyButton?.addEventListener("click", async () => {
+    const selected = currentChanges.filter((change) => change.selected);
+    if (selected.length === 0) {
+      logOutput && (logOutput.textContent = "No changes selected.");
+      return;
+    }
+
+    try {
+      applyButton.disabled = true;
+      const report = await window.go.main.App.ApplyChanges(selected);
+      renderResults(changesList, report);
+      currentChanges = [];
+      if (logOutput && report.journalPath) {
+        logOutput.textContent = `Journal written to ${report.journalPath}`;
+      }
+    } catch (error) {
+      console.error("Error applying changes:", error);
+      logOutput && (logOutput.textContent = `Error applying changes: ${error}`);
+      applyButton.disabled = false;
+      window.runtime?.LogError?.("Error applying changes: " + error);
+    }
+  });
 };
 
 // === Helper Functions ===
 
+const describeProgress = (progress) => {
+  const t = progress.totals;
+  switch (progress.kind) {
+    case "change_applied":
+    case "change_failed":
+      return `Applied ${t.changes_applied} changes, ${t.changes_failed} failed, ${t.bytes_reclaimed} bytes reclaimed` +
+        (progress.error ? ` (last error: ${progress.error})` : "");
+    case "planned":
+      return `Scanned ${t.dirs_scanned} folders and ${t.files_scanned} files, found ${t.changes_found} changes`;
+    default:
+      return `Scanning... ${t.dirs_scanned} folders, ${t.files_scanned} files, ${t.changes_found} changes so far`;
+  }
+};
+
 const renderChanges = (container, changes) => {
   container.innerHTML = "";
 
@@ -72,16 +127,57 @@ const renderChanges = (container, changes) => {
   setTimeout(applyFadeToOverflowingPaths, 0);
 };
 
-const createTableHeader = () => {
-  const thead = document.createElement("thead");
-  const row = document.createElement("tr");
+const renderResults = (container, report) => {
+  container.innerHTML = "";
+
+  const results = report?.results || [];
+  if (results.length === 0) {
+    container.innerHTML = NO_CHANGES_MSG;
+    return;
+  }
 
-  const headers = [
-    { text: "Select", width: "50px", align: "center" },
-    { text: "Type", width: "50px" },
+  const table = document.createElement("table");
+  table.className = "changes-table";
+  table.appendChild(createTableHeader([
+    { text: "Status", width: "80px" },
     { text: "File / Directory" },
     { text: "Target Name" },
-  ];
+  ]));
+
+  const tbody = document.createElement("tbody");
+  results.forEach(({ change, status, error }) => {
+    const row = document.createElement("tr");
+    row.className = `change-item result-${status}`;
+    if (error) row.title = error;
+
+    const statusCell = document.createElement("td");
+    statusCell.textContent = status;
+
+    const fileCell = document.createElement("td");
+    fileCell.appendChild(createOverflowSpan(change.target));
+
+    const targetCell = document.createElement("td");
+    targetCell.appendChild(createOverflowSpan(change.newName));
+
+    [statusCell, fileCell, targetCell].forEach((cell) => row.appendChild(cell));
+    tbody.appendChild(row);
+  });
+  table.appendChild(tbody);
+
+  container.appendChild(table);
+  setTimeout(applyFadeToOverflowingPaths, 0);
+};
+
+const CHANGE_HEADERS = [
+  { text: "Select", width: "50px", align: "center" },
+  { text: "Type", width: "50px" },
+  { text: "File / Directory" },
+  { text: "Target Name" },
+];
+
+const createTableHeader = (headers = CHANGE_HEADERS) => {
+  const thead = document.createElement("thead");
+  const row = document.createElement("tr");
 
   headers.forEach(({ text, width, align }) => {
     const th = document.createElement("th");
@@ -107,6 +203,11 @@ const createTableBody = (changes) => {
   changes.forEach((change) => {
     const row = document.createElement("tr");
     row.className = "change-item";
+    if (change.reason) row.title = change.reason.description;
+    if (change.collision) {
+      const { with: other, outcome } = change.collision;
+      row.title += `${row.title ? "\n" : ""}Collides with ${other}: ${outcome.replaceAll("_", " ")}`;
+    }
 
     // Checkbox
     const checkboxCell = document.createElement("td");
@@ -2,6 +2,10 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
-export function GetChanges(arg1:string,arg2:string,arg3:string):Promise<Array<main.Change>>;
+export function ApplyChanges(arg1:Array<main.Change>):Promise<main.ApplyReport>;
+
+export function GetChanges(arg1:string,arg2:string):Promise<Array<main.Change>>;
 
 export function OpenDirectoryDialog(arg1:string,arg2:string):Promise<string>;
+
+export function SetDeleteMode(arg1:string):Promise<void>;
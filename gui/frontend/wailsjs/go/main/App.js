@@ -2,10 +2,18 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function GetChanges(arg1, arg2, arg3) {
-  return window['go']['main']['App']['GetChanges'](arg1, arg2, arg3);
+export function ApplyChanges(arg1) {
+  return window['go']['main']['App']['ApplyChanges'](arg1);
+}
+
+export function GetChanges(arg1, arg2) {
+  return window['go']['main']['App']['GetChanges'](arg1, arg2);
 }
 
 export function OpenDirectoryDialog(arg1, arg2) {
   return window['go']['main']['App']['OpenDirectoryDialog'](arg1, arg2);
 }
+
+export function SetDeleteMode(arg1) {
+  return window['go']['main']['App']['SetDeleteMode'](arg1);
+}
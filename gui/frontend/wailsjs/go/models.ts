@@ -1,9 +1,49 @@
 export namespace main {
 	
+	export class Reason {
+	    ruleId: string;
+	    kind: string;
+	    pattern: string;
+	    conditions: string;
+	    source: string;
+	    description: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Reason(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ruleId = source["ruleId"];
+	        this.kind = source["kind"];
+	        this.pattern = source["pattern"];
+	        this.conditions = source["conditions"];
+	        this.source = source["source"];
+	        this.description = source["description"];
+	    }
+	}
+	export class Collision {
+	    with: string;
+	    policy: string;
+	    outcome: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Collision(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.with = source["with"];
+	        this.policy = source["policy"];
+	        this.outcome = source["outcome"];
+	    }
+	}
 	export class Change {
 	    type: string;
 	    target: string;
 	    newName: string;
+	    reason?: Reason;
+	    collision?: Collision;
 	    selected: boolean;
 	
 	    static createFrom(source: any = {}) {
@@ -15,8 +55,96 @@ export namespace main {
 	        this.type = source["type"];
 	        this.target = source["target"];
 	        this.newName = source["newName"];
+	        this.reason = this.convertValues(source["reason"], Reason);
+	        this.collision = this.convertValues(source["collision"], Collision);
 	        this.selected = source["selected"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ChangeResult {
+	    change: Change;
+	    status: string;
+	    error?: string;
+	    bytesFreed: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.change = this.convertValues(source["change"], Change);
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.bytesFreed = source["bytesFreed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ApplyReport {
+	    results: ChangeResult[];
+	    journalPath?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ApplyReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.results = this.convertValues(source["results"], ChangeResult);
+	        this.journalPath = source["journalPath"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
@@ -36,7 +36,11 @@ func CloseLogFile() error {
 }
 
 func initLogger(cfg LoggingConfig) error {
-	var writer io.Writer = os.Stdout
+	console := cfg.Console
+	if console == nil {
+		console = os.Stdout
+	}
+	var writer io.Writer = console
 
 	if cfg.LogToFile {
 		logDir := filepath.Dir(cfg.LogFilePath)
@@ -51,7 +55,7 @@ func initLogger(cfg LoggingConfig) error {
 		logFile = f
 
 		if cfg.AlsoPrintToConsole {
-			writer = io.MultiWriter(f, os.Stdout)
+			writer = io.MultiWriter(f, console)
 		} else {
 			writer = f
 		}
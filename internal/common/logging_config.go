@@ -1,8 +1,37 @@
 package common
 
+import "io"
+
 type LoggingConfig struct {
 	LogToFile          bool
 	LogFilePath        string
 	Debug              bool
-	AlsoPrintToConsole bool // ← new field
+	AlsoPrintToConsole bool      // ← new field
+	Console            io.Writer // where console logs go; os.Stdout if nil
+}
+
+// LoggingSettings is the logging section of a config file. Unset fields
+// leave the LoggingConfig they are applied to as is.
+type LoggingSettings struct {
+	ToFile  *bool  `json:"to_file,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Debug   *bool  `json:"debug,omitempty"`
+	Console *bool  `json:"also_print_to_console,omitempty"` // when logging to a file
+}
+
+// Apply returns cfg with the settings that are set
+func (s LoggingSettings) Apply(cfg LoggingConfig) LoggingConfig {
+	if s.ToFile != nil {
+		cfg.LogToFile = *s.ToFile
+	}
+	if s.Path != "" {
+		cfg.LogFilePath = s.Path
+	}
+	if s.Debug != nil {
+		cfg.Debug = *s.Debug
+	}
+	if s.Console != nil {
+		cfg.AlsoPrintToConsole = *s.Console
+	}
+	return cfg
 }
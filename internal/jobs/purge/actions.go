@@ -3,40 +3,62 @@ package purge
 import (
 	"fmt"
 
+	"github.com/spf13/afero"
+
 	"housekeeper/internal/common"
 )
 
+// Apply applies a single change, deleting files for good
 func Apply(change Change) error {
+	if change.Skipped() {
+		return nil
+	}
+	_, err := applyChange(AppFs, change, hardDeleter{fsys: AppFs})
+	return err
+}
+
+// applyChange applies a change on fsys and returns where a deleted file was
+// moved to, if the deleter keeps it
+func applyChange(fsys afero.Fs, change Change, d deleter) (string, error) {
 	switch change.Type {
 	case DeleteFile:
 		common.Info.Printf("Deleting %s\n", change.Target)
-		if err := UnlockPath(change.Target); err != nil {
+		if err := UnlockPath(fsys, change.Target); err != nil {
 			common.Warn.Printf("Unlock failed: %v", err)
-			return fmt.Errorf("unlocking %s: %w", change.Target, err)
+			return "", fmt.Errorf("unlocking %s: %w", change.Target, err)
 		}
-		if err := AppFs.Remove(change.Target); err != nil {
+		dest, err := d.remove(change.Target)
+		if err != nil {
 			common.Error.Printf("Failed to delete %s: %v", change.Target, err)
-			return fmt.Errorf("deleting %s: %w", change.Target, err)
+			return "", fmt.Errorf("deleting %s: %w", change.Target, err)
+		}
+		if dest != "" {
+			common.Info.Printf("Moved %s → %s\n", change.Target, dest)
 		}
+		return dest, nil
 	case RenameFile:
 		common.Info.Printf("Renaming %s → %s\n", change.Target, change.NewName)
-		if err := UnlockPath(change.Target); err != nil {
+		if err := UnlockPath(fsys, change.Target); err != nil {
 			common.Warn.Printf("Unlock failed: %v", err)
-			return fmt.Errorf("unlocking %s: %w", change.Target, err)
+			return "", fmt.Errorf("unlocking %s: %w", change.Target, err)
+		}
+		if err := verifyRenameTarget(fsys, change); err != nil {
+			common.Warn.Printf("Not renaming %s: %v", change.Target, err)
+			return "", err
 		}
-		if err := AppFs.Rename(change.Target, change.NewName); err != nil {
+		if err := fsys.Rename(change.Target, change.NewName); err != nil {
 			common.Error.Printf("Failed to rename %s: %v", change.Target, err)
-			return fmt.Errorf("renaming %s → %s: %w", change.Target, change.NewName, err)
+			return "", fmt.Errorf("renaming %s → %s: %w", change.Target, change.NewName, err)
 		}
 	case RemoveDir:
 		common.Info.Printf("Removing empty directory %s\n", change.Target)
-		if err := AppFs.Remove(change.Target); err != nil { // Use RemoveAll instead of Remove
+		if err := fsys.Remove(change.Target); err != nil { // Use RemoveAll instead of Remove
 			common.Error.Printf("Failed to remove dir %s: %v", change.Target, err)
-			return fmt.Errorf("removing dir %s: %w", change.Target, err)
+			return "", fmt.Errorf("removing dir %s: %w", change.Target, err)
 		}
 	default:
 		common.Warn.Printf("Unknown change type: %v", change.Type)
-		return fmt.Errorf("unknown change type: %v", change.Type)
+		return "", fmt.Errorf("unknown change type: %v", change.Type)
 	}
-	return nil
+	return "", nil
 }
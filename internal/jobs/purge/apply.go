@@ -1,24 +1,286 @@
 package purge
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ApplyStatus is what became of a change passed to ApplyAll
+type ApplyStatus string
+
+const (
+	StatusApplied ApplyStatus = "applied" // the change was made
+	StatusSkipped ApplyStatus = "skipped" // left alone: a collision skipped it, or its target drifted
+	StatusFailed  ApplyStatus = "failed"  // applying the change failed
+	StatusPending ApplyStatus = "pending" // never started as the context was done
 )
 
-// ApplyAll applies all given changes
-func ApplyAll(changes []Change) ([]Change, error) {
-	var applied []Change
-	var applyErr error
+// ApplyResult is the outcome of one change. An applied change may still
+// carry an error if journaling it failed.
+type ApplyResult struct {
+	Change     Change        `json:"change"`
+	Status     ApplyStatus   `json:"status"`
+	Err        error         `json:"-"`
+	Duration   time.Duration `json:"duration"`
+	BytesFreed int64         `json:"bytes_freed"` // size of a deleted file
+}
+
+// ApplyResults holds one result per change, in the order of the changes
+type ApplyResults []ApplyResult
+
+// Applied returns the changes that were made
+func (r ApplyResults) Applied() []Change {
+	return r.changes(StatusApplied)
+}
+
+// Pending returns the changes that were never started
+func (r ApplyResults) Pending() []Change {
+	return r.changes(StatusPending)
+}
+
+// Failed returns the results of the changes that failed
+func (r ApplyResults) Failed() ApplyResults {
+	var failed ApplyResults
+	for _, res := range r {
+		if res.Status == StatusFailed {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// BytesFreed returns the total size of the deleted files
+func (r ApplyResults) BytesFreed() int64 {
+	var n int64
+	for _, res := range r {
+		n += res.BytesFreed
+	}
+	return n
+}
 
-	for _, change := range changes {
-		if err := Apply(change); err != nil {
-			fmt.Printf("[ERROR] Failed to apply change: %v\n", err)
-			if applyErr == nil {
-				applyErr = err
+func (r ApplyResults) changes(status ApplyStatus) []Change {
+	var changes []Change
+	for _, res := range r {
+		if res.Status == status {
+			changes = append(changes, res.Change)
+		}
+	}
+	return changes
+}
+
+// ApplyAll applies all given changes, deleting files for good
+func ApplyAll(changes []Change) (ApplyResults, error) {
+	return ApplyAllWithOptions(changes, ApplyOptions{})
+}
+
+// ApplyAllWithOptions applies all given changes using the given options
+// and returns a result for each. Changes whose target drifted from its
+// plan-time fingerprint are skipped with a *StaleError; with opts.Strict
+// nothing is applied if any target drifted. With opts.Concurrency above 1,
+// independent changes run in parallel while dependent ones keep their
+// order. The error joins the errors of all results, in input order.
+func ApplyAllWithOptions(changes []Change, opts ApplyOptions) (ApplyResults, error) {
+	return ApplyAllContext(context.Background(), changes, opts)
+}
+
+// InterruptedError reports an apply that stopped because its context was
+// done. Pending lists the changes that were never started.
+type InterruptedError struct {
+	Pending []Change
+	Err     error // the context's error
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted with %d changes not applied: %v", len(e.Pending), e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error { return e.Err }
+
+// ApplyAllContext is ApplyAllWithOptions with a context. Once ctx is done
+// no further changes are started; those already running finish, and the
+// rest are left pending and reported in an *InterruptedError.
+func ApplyAllContext(ctx context.Context, changes []Change, opts ApplyOptions) (ApplyResults, error) {
+	mode, err := ParseDeleteMode(string(opts.DeleteMode))
+	if err != nil {
+		return nil, err
+	}
+	opts.DeleteMode = mode
+
+	if err := opts.Limits.check(changes); err != nil {
+		return nil, err
+	}
+
+	fsys := opts.Fs
+	if fsys == nil {
+		fsys = AppFs
+	}
+
+	d, err := newDeleter(fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var journal *journalWriter
+	if opts.JournalPath != "" {
+		if journal, err = openJournal(fsys, opts.JournalPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Strict {
+		var stale []error
+		freed := make(map[string]bool) // paths earlier changes delete or rename away
+		for _, change := range changes {
+			if change.Skipped() {
+				continue
 			}
-			continue
+			if err := ctx.Err(); err != nil {
+				return nil, &InterruptedError{Pending: pendingChanges(changes), Err: err}
+			}
+			err := verifyFingerprint(fsys, change)
+			if err == nil && change.Type == RenameFile && !freed[change.NewName] {
+				err = verifyRenameTarget(fsys, change)
+			}
+			if err != nil {
+				stale = append(stale, err)
+			}
+			if change.Type != RemoveDir {
+				freed[change.Target] = true
+			}
+		}
+		if len(stale) > 0 {
+			return nil, fmt.Errorf("plan is stale, nothing applied: %w", errors.Join(stale...))
+		}
+	}
+
+	a := &applier{fsys: fsys, deleter: d, journal: journal, mode: opts.DeleteMode, progress: newProgress(opts.Progress)}
+	results := make(ApplyResults, len(changes))
+	run := func(i int) {
+		if ctx.Err() != nil && !changes[i].Skipped() {
+			results[i] = ApplyResult{Change: changes[i], Status: StatusPending}
+			return
+		}
+		results[i] = a.apply(changes[i])
+	}
+
+	if opts.Concurrency > 1 {
+		runConcurrently(changes, opts.Concurrency, run)
+	} else {
+		for i := range changes {
+			run(i)
+		}
+	}
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
 		}
-		applied = append(applied, change)
 	}
+	if pending := results.Pending(); len(pending) > 0 {
+		errs = append(errs, &InterruptedError{Pending: pending, Err: ctx.Err()})
+	}
+	return results, errors.Join(errs...)
+}
 
-	return applied, applyErr
+// pendingChanges returns the changes that would be applied, leaving out
+// those skipped when planning
+func pendingChanges(changes []Change) []Change {
+	var pending []Change
+	for _, c := range changes {
+		if !c.Skipped() {
+			pending = append(pending, c)
+		}
+	}
+	return pending
+}
+
+// applier applies single changes and journals them. It is safe for
+// concurrent use.
+type applier struct {
+	fsys     afero.Fs
+	deleter  deleter
+	journal  *journalWriter
+	mode     DeleteMode
+	progress *progress
+}
+
+func (a *applier) apply(change Change) (res ApplyResult) {
+	res = ApplyResult{Change: change, Status: StatusSkipped}
+	if change.Skipped() {
+		return res
+	}
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	if res.Err = verifyFingerprint(a.fsys, change); res.Err != nil {
+		a.progress.changeFailed(change, res.Err)
+		return res
+	}
+
+	entry := newJournalEntry(a.fsys, change, a.mode)
+	size := deletedSize(a.fsys, change)
+	movedTo, err := applyChange(a.fsys, change, a.deleter)
+	if stale := (*StaleError)(nil); errors.As(err, &stale) {
+		res.Err = err // left alone, like a target that drifted
+		a.progress.changeFailed(change, err)
+		return res
+	}
+	if err != nil {
+		res.Status, res.Err = StatusFailed, err
+		a.progress.changeFailed(change, err)
+		return res
+	}
+	res.Status, res.BytesFreed = StatusApplied, size
+	a.progress.changeApplied(change, size)
+
+	if a.journal != nil {
+		entry.MovedTo = movedTo
+		if err := a.journal.append(entry); err != nil {
+			res.Err = fmt.Errorf("journaling %s: %w", change.Target, err)
+		}
+	}
+	return res
+}
+
+// deletedSize returns the size of the file a DeleteFile change removes from
+// the tree, or 0 for other changes
+func deletedSize(fsys afero.Fs, change Change) int64 {
+	if change.Type != DeleteFile {
+		return 0
+	}
+	if change.Fingerprint != nil {
+		return change.Fingerprint.Size // verified before applying
+	}
+	info, err := fsys.Stat(change.Target)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// check refuses changes that exceed the limits. Deleted sizes are taken
+// from the fingerprints, as planned.
+func (l SafetyLimits) check(changes []Change) error {
+	count, deleted := 0, int64(0)
+	for _, c := range changes {
+		if c.Skipped() {
+			continue
+		}
+		count++
+		if c.Type == DeleteFile && c.Fingerprint != nil {
+			deleted += c.Fingerprint.Size
+		}
+	}
+	if l.MaxChanges > 0 && count > l.MaxChanges {
+		return fmt.Errorf("%d changes exceed the safety limit of %d, nothing applied", count, l.MaxChanges)
+	}
+	if l.MaxDeleteBytes > 0 && deleted > int64(l.MaxDeleteBytes) {
+		return fmt.Errorf("deleting %d bytes exceeds the safety limit of %d, nothing applied", deleted, l.MaxDeleteBytes)
+	}
+	return nil
 }
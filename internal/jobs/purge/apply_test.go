@@ -1,6 +1,9 @@
 package purge
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"testing"
 
@@ -12,8 +15,7 @@ import (
 // TestApplyAll tests the ApplyAll function with various scenarios
 func TestApplyAll(t *testing.T) {
 	// Setup mock filesystem
-	fs := afero.NewMemMapFs()
-	AppFs = fs // Assuming appFs is the package-level filesystem variable
+	fs := useMemFs(t)
 
 	// Create some test files and directories
 	testDir := filepath.Join("test", "dir")
@@ -26,7 +28,7 @@ func TestApplyAll(t *testing.T) {
 	// Mock UnlockPath function for testing
 	originalUnlockPath := UnlockPath
 	defer func() { UnlockPath = originalUnlockPath }()
-	UnlockPath = func(path string) error { return nil }
+	UnlockPath = func(afero.Fs, string) error { return nil }
 
 	tests := []struct {
 		name        string
@@ -136,7 +138,8 @@ func TestApplyAll(t *testing.T) {
 			}
 
 			// Execute ApplyAll
-			applied, err := ApplyAll(tt.changes)
+			results, err := ApplyAll(tt.changes)
+			applied := results.Applied()
 
 			// Check error expectation
 			if tt.wantErr {
@@ -155,3 +158,148 @@ func TestApplyAll(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyAllContextInterrupted(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	var changes []Change
+	for i := range 5 {
+		file := filepath.Join(string(filepath.Separator), "scan", fmt.Sprintf("f%d.tmp", i))
+		require.NoError(t, afero.WriteFile(fs, file, nil, 0644))
+		changes = append(changes, Change{Type: DeleteFile, Target: file})
+	}
+
+	// Cancel while the second change is being applied
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error {
+		if calls++; calls == 2 {
+			cancel()
+		}
+		return nil
+	}
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	results, err := ApplyAllContext(ctx, changes, ApplyOptions{Fs: fs})
+	applied := results.Applied()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, changes[:2], applied, "the running change should finish")
+
+	var interrupted *InterruptedError
+	require.True(t, errors.As(err, &interrupted))
+	assert.Equal(t, changes[2:], interrupted.Pending)
+
+	for _, c := range interrupted.Pending {
+		exists, err := afero.Exists(fs, c.Target)
+		require.NoError(t, err)
+		assert.True(t, exists, "%s should not be touched", c.Target)
+	}
+}
+
+func TestApplyAllContextCanceledBeforeStart(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/scan/a.tmp", nil, 0644))
+	changes := []Change{
+		{Type: DeleteFile, Target: "/scan/a.tmp"},
+		{Type: RenameFile, Target: "/scan/b.JPG", NewName: "/scan/b.jpg", Collision: &Collision{Outcome: OutcomeSkipped}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, opts := range []ApplyOptions{{Fs: fs}, {Fs: fs, Concurrency: 4}, {Fs: fs, Strict: true}} {
+		results, err := ApplyAllContext(ctx, changes, opts)
+		applied := results.Applied()
+		assert.Empty(t, applied)
+
+		var interrupted *InterruptedError
+		require.True(t, errors.As(err, &interrupted))
+		assert.Equal(t, changes[:1], interrupted.Pending, "skipped changes are not pending")
+	}
+}
+
+func TestApplyAllResults(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	require.NoError(t, afero.WriteFile(fs, "/scan/a.tmp", make([]byte, 42), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/scan/b.tmp", nil, 0644))
+	info, err := fs.Stat("/scan/b.tmp")
+	require.NoError(t, err)
+	fp, err := newFingerprint(fs, "/scan/b.tmp", info, false)
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "/scan/b.tmp", []byte("edited"), 0644))
+
+	changes := []Change{
+		{Type: DeleteFile, Target: "/scan/a.tmp"},
+		{Type: DeleteFile, Target: "/scan/b.tmp", Fingerprint: fp},
+		{Type: RenameFile, Target: "/scan/c.JPG", NewName: "/scan/c.jpg", Collision: &Collision{Outcome: OutcomeSkipped}},
+		{Type: DeleteFile, Target: "/scan/missing.tmp"},
+	}
+	results, err := ApplyAllWithOptions(changes, ApplyOptions{Fs: fs})
+	require.Len(t, results, len(changes))
+
+	var statuses []ApplyStatus
+	for i, r := range results {
+		assert.Equal(t, changes[i], r.Change)
+		statuses = append(statuses, r.Status)
+	}
+	assert.Equal(t, []ApplyStatus{StatusApplied, StatusSkipped, StatusSkipped, StatusFailed}, statuses)
+
+	assert.Equal(t, int64(42), results[0].BytesFreed)
+	assert.NoError(t, results[0].Err)
+
+	var stale *StaleError
+	assert.ErrorAs(t, results[1].Err, &stale, "drifted targets are skipped with a StaleError")
+	assert.NoError(t, results[2].Err, "collision skips are not errors")
+	assert.Error(t, results[3].Err)
+
+	assert.ErrorIs(t, err, results[1].Err)
+	assert.ErrorIs(t, err, results[3].Err)
+	assert.Equal(t, int64(42), results.BytesFreed())
+}
+
+func TestApplyAllSafetyLimits(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fsys, "/scan/a.tmp", make([]byte, 600), 0644))
+	require.NoError(t, afero.WriteFile(fsys, "/scan/b.tmp", make([]byte, 600), 0644))
+	fingerprint := func(path string) *Fingerprint {
+		info, err := fsys.Stat(path)
+		require.NoError(t, err)
+		fp, err := newFingerprint(fsys, path, info, false)
+		require.NoError(t, err)
+		return fp
+	}
+	changes := []Change{
+		{Type: DeleteFile, Target: "/scan/a.tmp", Fingerprint: fingerprint("/scan/a.tmp")},
+		{Type: DeleteFile, Target: "/scan/b.tmp", Fingerprint: fingerprint("/scan/b.tmp")},
+		{Type: RenameFile, Target: "/scan/c.JPG", NewName: "/scan/c.jpg",
+			Collision: &Collision{With: "/scan/c.jpg", Policy: CollisionSkip, Outcome: OutcomeSkipped}},
+	}
+
+	tests := []struct {
+		name   string
+		limits SafetyLimits
+	}{
+		{name: "too many changes", limits: SafetyLimits{MaxChanges: 1}},
+		{name: "too many bytes", limits: SafetyLimits{MaxDeleteBytes: 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ApplyAllWithOptions(changes, ApplyOptions{Fs: fsys, Limits: tt.limits})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "safety limit")
+			assert.Nil(t, results)
+			exists, _ := afero.Exists(fsys, "/scan/a.tmp")
+			assert.True(t, exists, "nothing is applied")
+		})
+	}
+
+	// Skipped changes do not count
+	results, err := ApplyAllWithOptions(changes, ApplyOptions{Fs: fsys, Limits: SafetyLimits{MaxChanges: 2, MaxDeleteBytes: 1200}})
+	require.NoError(t, err)
+	assert.Len(t, results.Applied(), 2)
+}
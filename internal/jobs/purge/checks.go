@@ -1,23 +1,19 @@
 package purge
 
 import (
+    "io/fs"
     "path/filepath"
-    "strings"
 )
 
-func checkDelete(path string, extensions []string) *Change {
-    name := filepath.Base(path)
-    lower := strings.ToLower(name)
-
-    for _, ext := range extensions {
-        if strings.HasSuffix(lower, ext) {
-            return &Change{Type: DeleteFile, Target: path}
+// checkDelete returns a delete change if any rule matches. rel is the path
+// relative to the scanned root, used by glob rules; info is only needed by
+// rules with size or age predicates and may be nil otherwise.
+func checkDelete(path, rel string, info fs.FileInfo, rules []deleteRule) *Change {
+    for i := range rules {
+        if rules[i].matches(filepath.ToSlash(rel), info) {
+            return &Change{Type: DeleteFile, Target: path, Reason: rules[i].reason()}
         }
     }
 
-    if strings.HasPrefix(name, "._") || strings.HasPrefix(name, ".DS_Store") || strings.HasPrefix(name, ".") {
-        return &Change{Type: DeleteFile, Target: path}
-    }
-
     return nil
 }
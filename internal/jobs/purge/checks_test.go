@@ -1,15 +1,17 @@
 package purge
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
 func TestCheckDelete(t *testing.T) {
-	// Load production config using the same method as production code
-	cfg, err := LoadConfigWithOptions(LoadConfigOptions{})
+	// Use the built-in config, as production does without a config file
+	cfg := DefaultConfig()
+	rules, err := compileDeleteRules(cfg)
 	if err != nil {
-		t.Fatalf("Failed to load production config: %v", err)
+		t.Fatalf("Failed to compile production rules: %v", err)
 	}
 
 	// Core test cases for special files (not extension-dependent)
@@ -29,9 +31,14 @@ func TestCheckDelete(t *testing.T) {
 			want: &Change{Type: DeleteFile, Target: "/path/to/.DS_Store"},
 		},
 		{
-			name: "should delete hidden files",
+			name: "should keep hidden files by default",
 			path: "/path/to/.hidden",
-			want: &Change{Type: DeleteFile, Target: "/path/to/.hidden"},
+			want: nil,
+		},
+		{
+			name: "should keep dotfiles like .gitignore by default",
+			path: "/path/to/.gitignore",
+			want: nil,
 		},
 		{
 			name: "should not delete normal files without matching extensions",
@@ -43,7 +50,7 @@ func TestCheckDelete(t *testing.T) {
 	// Run core tests
 	for _, tt := range coreTests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := checkDelete(tt.path, cfg.ExtensionsToDelete)
+			got := checkDelete(tt.path, filepath.Base(tt.path), nil, rules)
 			assertChangeEquals(t, got, tt.want)
 		})
 	}
@@ -54,20 +61,83 @@ func TestCheckDelete(t *testing.T) {
 
 		t.Run("should delete files with "+ext+" extension", func(t *testing.T) {
 			path := "/path/to/file" + ext
-			got := checkDelete(path, cfg.ExtensionsToDelete)
+			got := checkDelete(path, filepath.Base(path), nil, rules)
 			want := &Change{Type: DeleteFile, Target: path}
 			assertChangeEquals(t, got, want)
 		})
 
 		t.Run("should delete files with uppercase "+ext+" extension", func(t *testing.T) {
 			path := "/path/to/file" + strings.ToUpper(ext)
-			got := checkDelete(path, cfg.ExtensionsToDelete)
+			got := checkDelete(path, filepath.Base(path), nil, rules)
 			want := &Change{Type: DeleteFile, Target: path}
 			assertChangeEquals(t, got, want)
 		})
 	}
 }
 
+func TestCheckDeleteHiddenFilesOptIn(t *testing.T) {
+	rules, err := compileDeleteRules(&Config{DeleteHiddenFiles: true})
+	if err != nil {
+		t.Fatalf("compileDeleteRules() error = %v", err)
+	}
+
+	path := "/path/to/.hidden"
+	assertChangeEquals(t, checkDelete(path, filepath.Base(path), nil, rules), &Change{Type: DeleteFile, Target: path})
+
+	path = "/path/to/visible.txt"
+	assertChangeEquals(t, checkDelete(path, filepath.Base(path), nil, rules), nil)
+}
+
+func TestCheckDeleteReason(t *testing.T) {
+	cfg := &Config{
+		ExtensionsToDelete: []string{".tmp"},
+		DeleteRules: []DeleteRule{
+			{ID: "thumbs", Kind: RuleGlob, Pattern: "Thumbs.db", Source: "rules.json"},
+			{Kind: RuleRegex, Pattern: `core\.[0-9]+`},
+		},
+		Sources: map[string]string{"extensions_to_delete:.tmp": "delete.json"},
+	}
+	rules, err := compileDeleteRules(cfg)
+	if err != nil {
+		t.Fatalf("compileDeleteRules() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want Reason
+	}{
+		{"/a/b.tmp", Reason{RuleID: "extensions_to_delete:.tmp", Kind: RuleSuffix, Pattern: ".tmp", Source: "delete.json"}},
+		{"/a/Thumbs.db", Reason{RuleID: "thumbs", Kind: RuleGlob, Pattern: "Thumbs.db", Source: "rules.json"}},
+		{"/a/core.42", Reason{RuleID: "delete_rules[1]", Kind: RuleRegex, Pattern: `core\.[0-9]+`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := checkDelete(tt.path, filepath.Base(tt.path), nil, rules)
+			if got == nil || got.Reason == nil {
+				t.Fatalf("checkDelete(%q) = %+v, want change with reason", tt.path, got)
+			}
+			if *got.Reason != tt.want {
+				t.Errorf("Reason = %+v, want %+v", *got.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestReasonString(t *testing.T) {
+	r := Reason{RuleID: "extensions_to_delete:.tmp", Kind: RuleSuffix, Pattern: ".tmp", Source: "delete.json"}
+	want := `suffix ".tmp" (rule extensions_to_delete:.tmp from delete.json)`
+	if got := r.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	r = Reason{RuleID: "empty_dir", Kind: RuleEmptyDir}
+	want = "empty_dir (rule empty_dir)"
+	if got := r.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
 func assertChangeEquals(t *testing.T, got, want *Change) {
 	t.Helper()
 
@@ -0,0 +1,281 @@
+package purge
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"housekeeper/internal/common"
+)
+
+// CollisionPolicy selects what happens when a rename would overwrite an
+// existing file or the result of another planned rename
+type CollisionPolicy string
+
+const (
+	CollisionSkip            CollisionPolicy = "skip"             // leave the file alone (default)
+	CollisionSuffix          CollisionPolicy = "suffix"           // rename to a free name like photo-1.jpg
+	CollisionKeepNewer       CollisionPolicy = "keep_newer"       // keep whichever file was modified last
+	CollisionDeleteIdentical CollisionPolicy = "delete_identical" // delete the file if both have the same content, else skip
+)
+
+// CollisionOutcome records how a collision was resolved
+type CollisionOutcome string
+
+const (
+	OutcomeSkipped          CollisionOutcome = "skipped"           // the rename is not applied
+	OutcomeSuffixed         CollisionOutcome = "suffixed"          // NewName got a numeric suffix
+	OutcomeDeletedOlder     CollisionOutcome = "deleted_older"     // this file lost to a newer one and is deleted
+	OutcomeReplaced         CollisionOutcome = "replaced"          // the rename wins over an older file, which is deleted
+	OutcomeDeletedDuplicate CollisionOutcome = "deleted_duplicate" // this file duplicated the other and is deleted
+)
+
+// Collision is attached to a Change whose rename collided with another file
+type Collision struct {
+	With    string           `json:"with"` // existing file, or source of the other planned rename
+	Policy  CollisionPolicy  `json:"policy"`
+	Outcome CollisionOutcome `json:"outcome"`
+}
+
+// ParseCollisionPolicy validates a policy name. An empty name means CollisionSkip.
+func ParseCollisionPolicy(s string) (CollisionPolicy, error) {
+	switch policy := CollisionPolicy(strings.ToLower(s)); policy {
+	case "":
+		return CollisionSkip, nil
+	case CollisionSkip, CollisionSuffix, CollisionKeepNewer, CollisionDeleteIdentical:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown rename collision policy %q (want skip, suffix, keep_newer or delete_identical)", s)
+}
+
+// Skipped reports whether the change was kept out of the run by a collision
+func (c Change) Skipped() bool {
+	return c.Collision != nil && c.Collision.Outcome == OutcomeSkipped
+}
+
+// resolveCollisions checks every planned rename against existing files on
+// fsys and earlier planned renames, in plan order, and resolves clashes with
+// policy
+func resolveCollisions(fsys afero.Fs, changes []Change, policy CollisionPolicy) ([]Change, error) {
+	resolved := make([]Change, 0, len(changes))
+	claimed := make(map[string]int) // rename destination → index in resolved
+
+	for _, c := range changes {
+		if c.Type != RenameFile {
+			resolved = append(resolved, c)
+			continue
+		}
+
+		with, planned, err := collidingFile(fsys, c, claimed, resolved)
+		if err != nil {
+			return nil, err
+		}
+		if with == "" {
+			claimed[c.NewName] = len(resolved)
+			resolved = append(resolved, c)
+			continue
+		}
+
+		common.Warn.Printf("Rename %s → %s collides with %s, resolving with %s", c.Target, c.NewName, with, policy)
+		collision := &Collision{With: with, Policy: policy}
+		c.Collision = collision
+
+		switch policy {
+		case CollisionSuffix:
+			if c.NewName, err = freeName(fsys, c.NewName, claimed); err != nil {
+				return nil, err
+			}
+			collision.Outcome = OutcomeSuffixed
+			claimed[c.NewName] = len(resolved)
+
+		case CollisionKeepNewer:
+			newer, err := isNewer(fsys, c.Target, with)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case !newer:
+				c.Type, c.NewName = DeleteFile, ""
+				c.Reason = collisionReason(policy, "older than "+with)
+				collision.Outcome = OutcomeDeletedOlder
+			case planned >= 0:
+				// The other rename's source is older: delete it instead
+				other := &resolved[planned]
+				other.Type, other.NewName = DeleteFile, ""
+				other.Reason = collisionReason(policy, "older than "+c.Target)
+				other.Collision = &Collision{With: c.Target, Policy: policy, Outcome: OutcomeDeletedOlder}
+				collision.Outcome = OutcomeReplaced
+				claimed[c.NewName] = len(resolved)
+			default:
+				resolved = append(resolved, Change{
+					Type:        DeleteFile,
+					Target:      with,
+					Reason:      collisionReason(policy, "older than "+c.Target),
+					Collision:   &Collision{With: c.Target, Policy: policy, Outcome: OutcomeDeletedOlder},
+					Fingerprint: fingerprintOrNil(fsys, with),
+				})
+				collision.Outcome = OutcomeReplaced
+				claimed[c.NewName] = len(resolved)
+			}
+
+		case CollisionDeleteIdentical:
+			same, err := sameContent(fsys, c.Target, with)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				c.Type, c.NewName = DeleteFile, ""
+				c.Reason = collisionReason(policy, "same content as "+with)
+				collision.Outcome = OutcomeDeletedDuplicate
+			} else {
+				collision.Outcome = OutcomeSkipped
+			}
+
+		default:
+			collision.Outcome = OutcomeSkipped
+		}
+
+		resolved = append(resolved, c)
+	}
+
+	return resolved, nil
+}
+
+// collidingFile returns the file the rename in c would clash with, and the
+// index of the planned rename it clashes with, or -1 for an existing file.
+// A case-only rename does not collide with the file it renames, as found
+// under the new name on case-insensitive filesystems.
+func collidingFile(fsys afero.Fs, c Change, claimed map[string]int, resolved []Change) (string, int, error) {
+	if i, ok := claimed[c.NewName]; ok {
+		return resolved[i].Target, i, nil
+	}
+	existing, err := renameTargetExists(fsys, c)
+	if err != nil || !existing {
+		return "", -1, err
+	}
+	return c.NewName, -1, nil
+}
+
+// renameTargetExists reports whether a file other than the target of the
+// rename in c exists at its new name
+func renameTargetExists(fsys afero.Fs, c Change) (bool, error) {
+	newInfo, err := lstat(fsys, c.NewName)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if strings.EqualFold(c.Target, c.NewName) {
+		targetInfo, err := lstat(fsys, c.Target)
+		if err != nil {
+			return false, err
+		}
+		if os.SameFile(targetInfo, newInfo) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// verifyRenameTarget checks that no other file appeared at the new name of
+// the rename in c since it was planned
+func verifyRenameTarget(fsys afero.Fs, c Change) error {
+	existing, err := renameTargetExists(fsys, c)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", c.NewName, err)
+	}
+	if existing {
+		return &StaleError{Change: c, Detail: c.NewName + " now exists"}
+	}
+	return nil
+}
+
+// collisionReason explains a delete that policy planned instead of a
+// rename, e.g. rename collision (keep_newer): older than /scan/a.JPEG
+func collisionReason(policy CollisionPolicy, why string) *Reason {
+	return &Reason{
+		RuleID:     shorthandID("rename_collisions", string(policy)),
+		Kind:       RuleCollision,
+		Pattern:    string(policy),
+		Conditions: why,
+	}
+}
+
+// freeName returns name with the first numeric suffix that neither exists
+// nor is claimed by another rename, e.g. photo-1.jpg
+func freeName(fsys afero.Fs, name string, claimed map[string]int) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", stem, i, ext)
+		if _, ok := claimed[candidate]; ok {
+			continue
+		}
+		_, err := lstat(fsys, candidate)
+		if os.IsNotExist(err) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// isNewer reports whether a was modified after b
+func isNewer(fsys afero.Fs, a, b string) (bool, error) {
+	infoA, err := lstat(fsys, a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := lstat(fsys, b)
+	if err != nil {
+		return false, err
+	}
+	return infoA.ModTime().After(infoB.ModTime()), nil
+}
+
+// sameContent reports whether two regular files have identical content
+func sameContent(fsys afero.Fs, a, b string) (bool, error) {
+	infos := make([]fs.FileInfo, 2)
+	for i, p := range []string{a, b} {
+		info, err := lstat(fsys, p)
+		if err != nil {
+			return false, err
+		}
+		if !info.Mode().IsRegular() {
+			return false, nil
+		}
+		infos[i] = info
+	}
+	if infos[0].Size() != infos[1].Size() {
+		return false, nil
+	}
+
+	hashA, err := hashFile(fsys, a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(fsys, b)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB, nil
+}
+
+// fingerprintOrNil fingerprints path, or returns nil if it cannot be read
+func fingerprintOrNil(fsys afero.Fs, path string) *Fingerprint {
+	info, err := lstat(fsys, path)
+	if err != nil {
+		return nil
+	}
+	fp, err := newFingerprint(fsys, path, info, false)
+	if err != nil {
+		return nil
+	}
+	return fp
+}
@@ -0,0 +1,264 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCollisions(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "scan")
+	p := func(name string) string { return filepath.Join(root, name) }
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := old.Add(24 * time.Hour)
+
+	type file struct {
+		data  string
+		mtime time.Time
+	}
+	tests := []struct {
+		name    string
+		files   map[string]file
+		changes []Change
+		policy  CollisionPolicy
+		want    []Change // compared on Type, Target, NewName and outcome
+	}{
+		{
+			name:    "no collision",
+			files:   map[string]file{"a.JPEG": {"a", old}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")}},
+			policy:  CollisionSkip,
+			want:    []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")}},
+		},
+		{
+			name:    "case-only rename does not collide with itself",
+			files:   map[string]file{"a.JPG": {"a", old}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPG"), NewName: p("a.jpg")}},
+			policy:  CollisionSkip,
+			want:    []Change{{Type: RenameFile, Target: p("a.JPG"), NewName: p("a.jpg")}},
+		},
+		{
+			name:    "case-only rename collides with another file",
+			files:   map[string]file{"a.JPG": {"upper", old}, "a.jpg": {"lower", old}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPG"), NewName: p("a.jpg")}},
+			policy:  CollisionSkip,
+			want: []Change{{Type: RenameFile, Target: p("a.JPG"), NewName: p("a.jpg"),
+				Collision: &Collision{Outcome: OutcomeSkipped}}},
+		},
+		{
+			name:    "skip existing file",
+			files:   map[string]file{"a.JPEG": {"a", old}, "a.jpg": {"b", old}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")}},
+			policy:  CollisionSkip,
+			want: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg"),
+				Collision: &Collision{Outcome: OutcomeSkipped}}},
+		},
+		{
+			name:  "suffix avoids existing and planned names",
+			files: map[string]file{"a.JPEG": {"a", old}, "a.jpeg": {"b", old}, "a.jpg": {"c", old}, "a-1.jpg": {"d", old}},
+			changes: []Change{
+				{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")},
+				{Type: RenameFile, Target: p("a.jpeg"), NewName: p("a.jpg")},
+			},
+			policy: CollisionSuffix,
+			want: []Change{
+				{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a-2.jpg"), Collision: &Collision{Outcome: OutcomeSuffixed}},
+				{Type: RenameFile, Target: p("a.jpeg"), NewName: p("a-3.jpg"), Collision: &Collision{Outcome: OutcomeSuffixed}},
+			},
+		},
+		{
+			name:    "keep newer deletes an older source",
+			files:   map[string]file{"a.JPEG": {"a", old}, "a.jpg": {"b", recent}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")}},
+			policy:  CollisionKeepNewer,
+			want: []Change{{Type: DeleteFile, Target: p("a.JPEG"),
+				Reason:    &Reason{Kind: RuleCollision, Pattern: "keep_newer", Conditions: "older than " + p("a.jpg")},
+				Collision: &Collision{Outcome: OutcomeDeletedOlder}}},
+		},
+		{
+			name:    "keep newer replaces an older existing file",
+			files:   map[string]file{"a.JPEG": {"a", recent}, "a.jpg": {"b", old}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")}},
+			policy:  CollisionKeepNewer,
+			want: []Change{
+				{Type: DeleteFile, Target: p("a.jpg"), Collision: &Collision{Outcome: OutcomeDeletedOlder},
+					Reason: &Reason{Kind: RuleCollision, Pattern: "keep_newer", Conditions: "older than " + p("a.JPEG")}},
+				{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg"), Collision: &Collision{Outcome: OutcomeReplaced}},
+			},
+		},
+		{
+			name:  "keep newer between two planned renames",
+			files: map[string]file{"a.JPEG": {"a", old}, "a.jpeg": {"b", recent}},
+			changes: []Change{
+				{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")},
+				{Type: RenameFile, Target: p("a.jpeg"), NewName: p("a.jpg")},
+			},
+			policy: CollisionKeepNewer,
+			want: []Change{
+				{Type: DeleteFile, Target: p("a.JPEG"), Collision: &Collision{Outcome: OutcomeDeletedOlder},
+					Reason: &Reason{Kind: RuleCollision, Pattern: "keep_newer", Conditions: "older than " + p("a.jpeg")}},
+				{Type: RenameFile, Target: p("a.jpeg"), NewName: p("a.jpg"), Collision: &Collision{Outcome: OutcomeReplaced}},
+			},
+		},
+		{
+			name:    "delete identical duplicate",
+			files:   map[string]file{"a.JPEG": {"same", old}, "a.jpg": {"same", recent}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")}},
+			policy:  CollisionDeleteIdentical,
+			want: []Change{{Type: DeleteFile, Target: p("a.JPEG"),
+				Reason:    &Reason{Kind: RuleCollision, Pattern: "delete_identical", Conditions: "same content as " + p("a.jpg")},
+				Collision: &Collision{Outcome: OutcomeDeletedDuplicate}}},
+		},
+		{
+			name:    "delete identical skips different content",
+			files:   map[string]file{"a.JPEG": {"abcd", old}, "a.jpg": {"efgh", old}},
+			changes: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg")}},
+			policy:  CollisionDeleteIdentical,
+			want: []Change{{Type: RenameFile, Target: p("a.JPEG"), NewName: p("a.jpg"),
+				Collision: &Collision{Outcome: OutcomeSkipped}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := useMemFs(t)
+			for name, f := range tt.files {
+				require.NoError(t, afero.WriteFile(fs, p(name), []byte(f.data), 0644))
+				require.NoError(t, fs.Chtimes(p(name), f.mtime, f.mtime))
+			}
+
+			got, err := resolveCollisions(fs, tt.changes, tt.policy)
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i, want := range tt.want {
+				assert.Equal(t, want.Type, got[i].Type, "change %d", i)
+				assert.Equal(t, want.Target, got[i].Target, "change %d", i)
+				assert.Equal(t, want.NewName, got[i].NewName, "change %d", i)
+				if want.Reason != nil {
+					require.NotNil(t, got[i].Reason, "change %d", i)
+					assert.Equal(t, want.Reason.String(), got[i].Reason.String(), "change %d", i)
+				}
+				if want.Collision == nil {
+					assert.Nil(t, got[i].Collision, "change %d", i)
+					continue
+				}
+				require.NotNil(t, got[i].Collision, "change %d", i)
+				assert.Equal(t, want.Collision.Outcome, got[i].Collision.Outcome, "change %d", i)
+				assert.Equal(t, tt.policy, got[i].Collision.Policy, "change %d", i)
+			}
+		})
+	}
+}
+
+func TestApplyAllSkipsCollidingRename(t *testing.T) {
+	fs := useMemFs(t)
+	src := filepath.Join(string(filepath.Separator), "scan", "a.JPEG")
+	dst := filepath.Join(string(filepath.Separator), "scan", "a.jpg")
+	require.NoError(t, afero.WriteFile(fs, src, []byte("new"), 0644))
+	require.NoError(t, afero.WriteFile(fs, dst, []byte("existing"), 0644))
+
+	results, err := ApplyAll([]Change{{Type: RenameFile, Target: src, NewName: dst,
+		Collision: &Collision{With: dst, Policy: CollisionSkip, Outcome: OutcomeSkipped}}})
+	applied := results.Applied()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	data, err := afero.ReadFile(fs, dst)
+	require.NoError(t, err)
+	assert.Equal(t, "existing", string(data), "existing file must not be overwritten")
+}
+
+func TestCaseOnlyRenameOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	upper := filepath.Join(dir, "photo.JPG")
+	lower := filepath.Join(dir, "photo.jpg")
+	require.NoError(t, os.WriteFile(upper, []byte("upper"), 0644))
+	fsys := afero.NewOsFs()
+	rename := []Change{{Type: RenameFile, Target: upper, NewName: lower}}
+
+	got, err := resolveCollisions(fsys, rename, CollisionSkip)
+	require.NoError(t, err)
+	assert.Nil(t, got[0].Collision, "the file does not collide with itself")
+
+	if _, err := os.Stat(lower); err == nil {
+		t.Skip("case-insensitive filesystem")
+	}
+	require.NoError(t, os.WriteFile(lower, []byte("lower"), 0644))
+	got, err = resolveCollisions(fsys, rename, CollisionSkip)
+	require.NoError(t, err)
+	require.NotNil(t, got[0].Collision)
+	assert.Equal(t, OutcomeSkipped, got[0].Collision.Outcome)
+}
+
+func TestApplyAllSkipsRenameOntoNewFile(t *testing.T) {
+	fs := useMemFs(t)
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	src := filepath.Join(string(filepath.Separator), "scan", "a.JPEG")
+	dst := filepath.Join(string(filepath.Separator), "scan", "a.jpg")
+	require.NoError(t, afero.WriteFile(fs, src, []byte("new"), 0644))
+	changes, err := resolveCollisions(fs, []Change{{Type: RenameFile, Target: src, NewName: dst}}, CollisionSkip)
+	require.NoError(t, err)
+	require.Nil(t, changes[0].Collision)
+
+	// Created after planning
+	require.NoError(t, afero.WriteFile(fs, dst, []byte("existing"), 0644))
+
+	results, err := ApplyAll(changes)
+	var stale *StaleError
+	require.ErrorAs(t, err, &stale)
+	assert.Equal(t, StatusSkipped, results[0].Status)
+	data, err := afero.ReadFile(fs, dst)
+	require.NoError(t, err)
+	assert.Equal(t, "existing", string(data), "existing file must not be overwritten")
+
+	_, err = ApplyAllWithOptions(changes, ApplyOptions{Strict: true})
+	assert.ErrorContains(t, err, "plan is stale")
+}
+
+func TestApplyAllStrictKeepNewer(t *testing.T) {
+	fs := useMemFs(t)
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := filepath.Join(string(filepath.Separator), "scan", "a.JPEG")
+	dst := filepath.Join(string(filepath.Separator), "scan", "a.jpg")
+	require.NoError(t, afero.WriteFile(fs, src, []byte("newer"), 0644))
+	require.NoError(t, afero.WriteFile(fs, dst, []byte("older"), 0644))
+	require.NoError(t, fs.Chtimes(dst, old, old))
+
+	changes, err := resolveCollisions(fs, []Change{{Type: RenameFile, Target: src, NewName: dst}}, CollisionKeepNewer)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "rename collision (keep_newer): older than "+src, changes[0].Reason.String())
+
+	// The older file is deleted before the rename takes its name
+	results, err := ApplyAllWithOptions(changes, ApplyOptions{Strict: true})
+	require.NoError(t, err)
+	assert.Len(t, results.Applied(), 2)
+	data, err := afero.ReadFile(fs, dst)
+	require.NoError(t, err)
+	assert.Equal(t, "newer", string(data))
+}
+
+func TestParseCollisionPolicy(t *testing.T) {
+	got, err := ParseCollisionPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, CollisionSkip, got)
+
+	got, err = ParseCollisionPolicy("Keep_Newer")
+	assert.NoError(t, err)
+	assert.Equal(t, CollisionKeepNewer, got)
+
+	_, err = ParseCollisionPolicy("overwrite")
+	assert.Error(t, err)
+}
@@ -3,57 +3,238 @@ package purge
 
 import (
     "encoding/json"
+    "errors"
     "fmt"
     "io/fs"
     "os"
     "path/filepath"
-    "runtime"
+    "slices"
+
+    "housekeeper/internal/common"
 )
 
-// LoadConfigWithOptions loads config using optional paths + fallback by default
-func LoadConfigWithOptions(opts LoadConfigOptions) (*Config, error) {
-    deletePath := opts.DeleteConfigPath
-    replacePath := opts.ReplaceConfigPath
+// LoadConfig loads the config file found by FindConfig, or the built-in
+// defaults if there is none, and validates it. A config with errors fails
+// with a *ValidationError.
+func LoadConfig(opts LoadConfigOptions) (*Config, error) {
+    cfg, err := loadConfig(opts)
+    if err != nil {
+        return nil, err
+    }
+    if err := checkConfig(cfg); err != nil {
+        return nil, err
+    }
+    return cfg, nil
+}
 
-    // Always try fallback if paths missing
-    if deletePath == "" || replacePath == "" {
-        root, err := findProjectRoot()
+func loadConfig(opts LoadConfigOptions) (*Config, error) {
+    loc, err := FindConfig(opts)
+    if err != nil {
+        return nil, err
+    }
+
+    // Legacy files given explicitly apply over the config file, or on their
+    // own without one, as they did before there was a config file
+    var cfg *Config
+    switch {
+    case loc.Path != "":
+        file, err := ReadConfigFile(loc.Path)
         if err != nil {
-            return nil, fmt.Errorf("unable to locate project root: %w", err)
+            return nil, err
         }
+        cfg = file.Config(loc.Path)
+    case opts.hasLegacyFiles():
+        cfg = &Config{}
+    default:
+        cfg = DefaultConfig()
+    }
+
+    legacy, err := loadLegacyConfig(opts, "")
+    if err != nil {
+        return nil, err
+    }
+    mergeConfig(cfg, legacy)
+    return cfg, nil
+}
+
+// checkConfig rejects a config ValidateConfig finds errors in, and keeps
+// its warnings in cfg.Warnings
+func checkConfig(cfg *Config) error {
+    warnings, err := validationError(ValidateConfig(cfg))
+    if err != nil {
+        return err
+    }
+    cfg.Warnings = warnings
+    return nil
+}
 
+// loadLegacyConfig loads the layout used before the versioned config file:
+// a bare array of extensions to delete, a bare map of extension
+// replacements, and optional delete rules and settings files. Paths not
+// given in opts default to their place under dir. With no dir, only the
+// files given are loaded.
+func loadLegacyConfig(opts LoadConfigOptions, dir string) (*Config, error) {
+    deletePath := opts.DeleteConfigPath
+    replacePath := opts.ReplaceConfigPath
+    rulesPath := opts.RulesConfigPath
+    settingsPath := opts.SettingsPath
+
+    if dir != "" {
         if deletePath == "" {
-            deletePath = filepath.Join(root, "userconfigs", "extensions_to_delete.json")
+            deletePath = filepath.Join(dir, "userconfigs", "extensions_to_delete.json")
         }
         if replacePath == "" {
-            replacePath = filepath.Join(root, "userconfigs", "extension_replacements.json")
+            replacePath = filepath.Join(dir, "userconfigs", "extension_replacements.json")
+        }
+        if rulesPath == "" {
+            rulesPath = filepath.Join(dir, "userconfigs", "delete_rules.json")
+        }
+        if settingsPath == "" {
+            settingsPath = filepath.Join(dir, "config.json")
         }
     }
 
-    delData, err := readJSONFile(deletePath)
-    if err != nil {
-        return nil, fmt.Errorf("reading delete config: %w", err)
+    // The two original files are required unless the whole layout is optional
+    var exts []string
+    if err := readOptionalJSONFile(deletePath, dir != "" || opts.DeleteConfigPath != "", &exts); err != nil {
+        return nil, fmt.Errorf("delete config: %w", err)
     }
 
-    replData, err := readJSONFile(replacePath)
-    if err != nil {
-        return nil, fmt.Errorf("reading replace config: %w", err)
+    var repls map[string]string
+    if err := readOptionalJSONFile(replacePath, dir != "" || opts.ReplaceConfigPath != "", &repls); err != nil {
+        return nil, fmt.Errorf("replace config: %w", err)
     }
 
-    var exts []string
-    if err := json.Unmarshal(delData, &exts); err != nil {
-        return nil, fmt.Errorf("parsing delete config: %w", err)
+    // The rules file is optional unless explicitly requested
+    var rules []DeleteRule
+    if err := readOptionalJSONFile(rulesPath, opts.RulesConfigPath != "", &rules); err != nil {
+        return nil, fmt.Errorf("rules config: %w", err)
     }
 
-    var repls map[string]string
-    if err := json.Unmarshal(replData, &repls); err != nil {
-        return nil, fmt.Errorf("parsing replace config: %w", err)
+    // config.json is optional too and is merged over the userconfigs files
+    var settings Config
+    if err := readOptionalJSONFile(settingsPath, opts.SettingsPath != "", &settings); err != nil {
+        return nil, fmt.Errorf("settings config: %w", err)
+    }
+
+    cfg := &Config{}
+    for _, part := range []struct {
+        cfg  *Config
+        path string
+    }{
+        {&Config{ExtensionsToDelete: exts}, deletePath},
+        {&Config{ExtensionReplacements: repls}, replacePath},
+        {&Config{DeleteRules: rules}, rulesPath},
+        {&settings, settingsPath},
+    } {
+        stampSources(part.cfg, part.path)
+        mergeConfig(cfg, part.cfg)
     }
+    return cfg, nil
+}
+
+// mergeConfig adds the rules from src to dst. Lists are appended without
+// duplicates, replacements and sources from src win, and DeleteHiddenFiles
+// and FingerprintHash are enabled if either side enables them. A
+// RenameCollisions policy, safety limits and logging settings set in src
+// win.
+func mergeConfig(dst, src *Config) {
+    dst.ExtensionsToDelete = appendUnique(dst.ExtensionsToDelete, src.ExtensionsToDelete...)
+    dst.PrefixesToDelete = appendUnique(dst.PrefixesToDelete, src.PrefixesToDelete...)
+    dst.DeleteRules = append(dst.DeleteRules, src.DeleteRules...)
+    dst.Excludes = appendUnique(dst.Excludes, src.Excludes...)
+    dst.DeleteHiddenFiles = dst.DeleteHiddenFiles || src.DeleteHiddenFiles
+    dst.FingerprintHash = dst.FingerprintHash || src.FingerprintHash
+    if src.RenameCollisions != "" {
+        dst.RenameCollisions = src.RenameCollisions
+    }
+    if src.Safety.MaxChanges != 0 {
+        dst.Safety.MaxChanges = src.Safety.MaxChanges
+    }
+    if src.Safety.MaxDeleteBytes != 0 {
+        dst.Safety.MaxDeleteBytes = src.Safety.MaxDeleteBytes
+    }
+    dst.Logging = mergeLogging(dst.Logging, src.Logging)
+
+    if len(src.ExtensionReplacements) > 0 && dst.ExtensionReplacements == nil {
+        dst.ExtensionReplacements = make(map[string]string, len(src.ExtensionReplacements))
+    }
+    for from, to := range src.ExtensionReplacements {
+        dst.ExtensionReplacements[from] = to
+    }
+
+    if len(src.Sources) > 0 && dst.Sources == nil {
+        dst.Sources = make(map[string]string, len(src.Sources))
+    }
+    for id, source := range src.Sources {
+        dst.Sources[id] = source
+    }
+}
+
+func mergeLogging(dst, src common.LoggingSettings) common.LoggingSettings {
+    if src.ToFile != nil {
+        dst.ToFile = src.ToFile
+    }
+    if src.Path != "" {
+        dst.Path = src.Path
+    }
+    if src.Debug != nil {
+        dst.Debug = src.Debug
+    }
+    if src.Console != nil {
+        dst.Console = src.Console
+    }
+    return dst
+}
 
-    return &Config{
-        ExtensionsToDelete:    exts,
-        ExtensionReplacements: repls,
-    }, nil
+// stampSources records path as the origin of every rule and setting in cfg
+func stampSources(cfg *Config, path string) {
+    if cfg.Sources == nil {
+        cfg.Sources = make(map[string]string)
+    }
+    for _, ext := range cfg.ExtensionsToDelete {
+        cfg.Sources[shorthandID("extensions_to_delete", ext)] = path
+    }
+    for _, prefix := range cfg.PrefixesToDelete {
+        cfg.Sources[shorthandID("prefixes_to_delete", prefix)] = path
+    }
+    for from := range cfg.ExtensionReplacements {
+        cfg.Sources[shorthandID("extension_replacements", from)] = path
+    }
+    if cfg.DeleteHiddenFiles {
+        cfg.Sources["delete_hidden_files"] = path
+    }
+    for i := range cfg.DeleteRules {
+        cfg.DeleteRules[i].Source = path
+    }
+    for _, pattern := range cfg.Excludes {
+        cfg.Sources[shorthandID("excludes", pattern)] = path
+    }
+
+    // Settings only have a source when they are set
+    for id, set := range map[string]bool{
+        "rename_collisions":             cfg.RenameCollisions != "",
+        "fingerprint_hash":              cfg.FingerprintHash,
+        "safety.max_changes":            cfg.Safety.MaxChanges != 0,
+        "safety.max_delete_bytes":       cfg.Safety.MaxDeleteBytes != 0,
+        "logging.to_file":               cfg.Logging.ToFile != nil,
+        "logging.path":                  cfg.Logging.Path != "",
+        "logging.debug":                 cfg.Logging.Debug != nil,
+        "logging.also_print_to_console": cfg.Logging.Console != nil,
+    } {
+        if set {
+            cfg.Sources[id] = path
+        }
+    }
+}
+
+func appendUnique(list []string, items ...string) []string {
+    for _, item := range items {
+        if !slices.Contains(list, item) {
+            list = append(list, item)
+        }
+    }
+    return list
 }
 
 func readJSONFile(path string) ([]byte, error) {
@@ -67,19 +248,18 @@ func readJSONFile(path string) ([]byte, error) {
     return data, nil
 }
 
-// findProjectRoot finds the root of the project by looking upward until it finds userconfigs/
-func findProjectRoot() (string, error) {
-    _, filename, _, _ := runtime.Caller(0)
-    dir := filepath.Dir(filename)
-
-    for {
-        if _, err := os.Stat(filepath.Join(dir, "userconfigs")); err == nil {
-            return dir, nil
-        }
-        parent := filepath.Dir(dir)
-        if parent == dir {
-            return "", fmt.Errorf("could not find project root")
+// readOptionalJSONFile decodes the file at path into v. A missing file is only
+// an error when the path was given explicitly.
+func readOptionalJSONFile(path string, explicit bool, v any) error {
+    data, err := readJSONFile(path)
+    if err != nil {
+        if errors.Is(err, fs.ErrNotExist) && !explicit {
+            return nil
         }
-        dir = parent
+        return fmt.Errorf("reading %s: %w", path, err)
+    }
+    if err := json.Unmarshal(data, v); err != nil {
+        return fmt.Errorf("parsing %s: %w", path, err)
     }
+    return nil
 }
\ No newline at end of file
@@ -0,0 +1,161 @@
+package purge
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"housekeeper/internal/common"
+)
+
+// ConfigVersion is the version of the config file read by ReadConfigFile
+const ConfigVersion = 1
+
+// ConfigFile is the versioned config document, holding all the settings in
+// one file. It replaces the legacy layout of separate files.
+type ConfigFile struct {
+	Version  int                    `json:"version"`
+	Delete   DeleteSettings         `json:"delete"`
+	Rename   RenameSettings         `json:"rename"`
+	Excludes []string               `json:"excludes,omitempty"` // gitignore-style patterns skipped by the walk
+	Safety   SafetySettings         `json:"safety"`
+	Logging  common.LoggingSettings `json:"logging"`
+}
+
+// DeleteSettings select the files to delete
+type DeleteSettings struct {
+	Extensions  *StringList  `json:"extensions,omitempty"`   // shorthand for suffix rules
+	Prefixes    []string     `json:"prefixes,omitempty"`     // shorthand for prefix rules
+	HiddenFiles bool         `json:"hidden_files,omitempty"` // every dotfile
+	Rules       []DeleteRule `json:"rules,omitempty"`
+}
+
+// RenameSettings select the files to rename and how collisions are handled
+type RenameSettings struct {
+	ExtensionReplacements *StringMap      `json:"extension_replacements,omitempty"`
+	Collisions            CollisionPolicy `json:"collisions,omitempty"` // skip by default
+}
+
+// SafetySettings guard against applying a plan that no longer fits
+type SafetySettings struct {
+	FingerprintHash bool `json:"fingerprint_hash,omitempty"` // also hash file contents when planning
+	SafetyLimits
+}
+
+// ReadConfigFile reads a config file in JSON, YAML or TOML, by its
+// extension, rejecting unknown fields and versions other than
+// ConfigVersion. Problems with its settings are *ConfigError.
+func ReadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("reading config %s: %w", path, fs.ErrNotExist)
+		}
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	return parseConfigFile(data, path)
+}
+
+// parseConfigFile parses the config file read from path, in the format
+// of its extension
+func parseConfigFile(data []byte, path string) (*ConfigFile, error) {
+	tree, lines, err := parseConfigTree(data, path)
+	if err != nil {
+		return nil, err
+	}
+	d := &configDecoder{path: path, lines: lines}
+
+	// Check the version first, a newer file may well have unknown fields
+	settings, _ := tree.(map[string]any)
+	if tree != nil && settings == nil {
+		return nil, d.errorAt("", fmt.Errorf("expected a table of settings, got %s", describeValue(tree)))
+	}
+	var version int
+	if err := d.decode(settings["version"], reflect.ValueOf(&version).Elem(), "version"); err != nil {
+		return nil, err
+	}
+	switch {
+	case version == 0:
+		return nil, fmt.Errorf("config %s has no version; convert legacy files with: housekeeper config migrate", path)
+	case version != ConfigVersion:
+		return nil, d.errorAt("version", fmt.Errorf("unsupported version %d (want %d)", version, ConfigVersion))
+	}
+
+	var file ConfigFile
+	if err := d.decode(settings, reflect.ValueOf(&file).Elem(), ""); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Config returns the settings of the file, with path recorded as the
+// source of every rule. Edits of list and map settings apply to empty ones.
+func (f *ConfigFile) Config(path string) *Config {
+	cfg := &Config{
+		ExtensionsToDelete:    f.Delete.Extensions.apply(nil),
+		ExtensionReplacements: f.Rename.ExtensionReplacements.apply(nil),
+		DeleteRules:           slices.Clone(f.Delete.Rules), // sources are stamped on the copy
+		PrefixesToDelete:      f.Delete.Prefixes,
+		DeleteHiddenFiles:     f.Delete.HiddenFiles,
+		Excludes:              f.Excludes,
+		FingerprintHash:       f.Safety.FingerprintHash,
+		RenameCollisions:      f.Rename.Collisions,
+		Safety:                f.Safety.SafetyLimits,
+		Logging:               f.Logging,
+	}
+	stampSources(cfg, path)
+	return cfg
+}
+
+// NewConfigFile returns cfg as a config document
+func NewConfigFile(cfg *Config) *ConfigFile {
+	return &ConfigFile{
+		Version: ConfigVersion,
+		Delete: DeleteSettings{
+			Extensions:  replaceList(cfg.ExtensionsToDelete),
+			Prefixes:    cfg.PrefixesToDelete,
+			HiddenFiles: cfg.DeleteHiddenFiles,
+			Rules:       cfg.DeleteRules,
+		},
+		Rename: RenameSettings{
+			ExtensionReplacements: replaceMap(cfg.ExtensionReplacements),
+			Collisions:            cfg.RenameCollisions,
+		},
+		Excludes: cfg.Excludes,
+		Safety:   SafetySettings{FingerprintHash: cfg.FingerprintHash, SafetyLimits: cfg.Safety},
+		Logging:  cfg.Logging,
+	}
+}
+
+// MigrateConfig converts the legacy layout into a config document. Paths
+// not given in opts default to their place under opts.Dir, or the working
+// dir.
+func MigrateConfig(opts LoadConfigOptions) (*ConfigFile, error) {
+	cfg, err := loadLegacyConfig(opts, cmp.Or(opts.Dir, "."))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConfig(cfg); err != nil {
+		return nil, err
+	}
+	return NewConfigFile(cfg), nil
+}
+
+// WriteConfigFile writes a config document as indented JSON, so path must
+// not have the extension of another format
+func WriteConfigFile(path string, file *ConfigFile) error {
+	if format := ConfigFormatOf(path); format != FormatJSON {
+		return fmt.Errorf("writing %s: config files are written as JSON, not %s", path, strings.ToUpper(string(format)))
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
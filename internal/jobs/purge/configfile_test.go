@@ -0,0 +1,118 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLegacyLayout writes the legacy config files under dir
+func writeLegacyLayout(t *testing.T, dir string) {
+	t.Helper()
+	files := map[string]string{
+		"userconfigs/extensions_to_delete.json":   `[".tmp", ".bak"]`,
+		"userconfigs/extension_replacements.json": `{".jpeg": ".jpg"}`,
+		"userconfigs/delete_rules.json":           `[{"id": "dumps", "kind": "regex", "pattern": "core\\.[0-9]+"}]`,
+		"config.json":                             `{"prefixes_to_delete": ["._"], "excludes": [".git/"], "rename_collisions": "suffix"}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DirConfigName)
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"version": 1,
+		"delete": {"extensions": [".tmp"], "prefixes": ["._"], "rules": [{"kind": "glob", "pattern": "**/*.bak"}]},
+		"rename": {"extension_replacements": {".jpeg": ".jpg"}, "collisions": "suffix"},
+		"excludes": [".git/"],
+		"safety": {"fingerprint_hash": true, "max_changes": 100, "max_delete_bytes": "1GiB"},
+		"logging": {"to_file": false, "debug": true}
+	}`), 0644))
+
+	file, err := ReadConfigFile(path)
+	require.NoError(t, err)
+	cfg := file.Config(path)
+
+	assert.Equal(t, []string{".tmp"}, cfg.ExtensionsToDelete)
+	assert.Equal(t, []string{"._"}, cfg.PrefixesToDelete)
+	assert.Equal(t, map[string]string{".jpeg": ".jpg"}, cfg.ExtensionReplacements)
+	assert.Equal(t, CollisionSuffix, cfg.RenameCollisions)
+	assert.Equal(t, []string{".git/"}, cfg.Excludes)
+	assert.True(t, cfg.FingerprintHash)
+	assert.Equal(t, SafetyLimits{MaxChanges: 100, MaxDeleteBytes: 1 << 30}, cfg.Safety)
+	require.NotNil(t, cfg.Logging.ToFile)
+	assert.False(t, *cfg.Logging.ToFile)
+	assert.Equal(t, path, cfg.Sources["extensions_to_delete:.tmp"])
+	assert.Equal(t, path, cfg.DeleteRules[0].Source)
+
+	// Writing the settings back gives the same file
+	again := filepath.Join(dir, "again.json")
+	require.NoError(t, WriteConfigFile(again, NewConfigFile(cfg)))
+	reread, err := ReadConfigFile(again)
+	require.NoError(t, err)
+	assert.Equal(t, file, reread)
+}
+
+func TestReadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "no version", data: `{"delete": {"extensions": [".tmp"]}}`, wantErr: "config migrate"},
+		{name: "newer version", data: `{"version": 2, "shiny": true}`, wantErr: "unsupported version 2"},
+		{name: "unknown field", data: `{"version": 1, "delete": {"extension": [".tmp"]}}`, wantErr: `unknown field "extension"`},
+		{name: "legacy settings", data: `{"version": 1, "extensions_to_delete": [".tmp"]}`, wantErr: "unknown field"},
+		{name: "not json", data: `version = 1`, wantErr: DirConfigName + ":1: invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DirConfigName)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0644))
+			_, err := ReadConfigFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	isolateConfig(t)
+	dir := t.TempDir()
+	writeLegacyLayout(t, dir)
+	legacy, err := LoadConfig(LoadConfigOptions{
+		Dir:               dir,
+		DeleteConfigPath:  filepath.Join(dir, "userconfigs", "extensions_to_delete.json"),
+		ReplaceConfigPath: filepath.Join(dir, "userconfigs", "extension_replacements.json"),
+		RulesConfigPath:   filepath.Join(dir, "userconfigs", "delete_rules.json"),
+		SettingsPath:      filepath.Join(dir, "config.json"),
+	})
+	require.NoError(t, err)
+
+	file, err := MigrateConfig(LoadConfigOptions{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, ConfigVersion, file.Version)
+
+	path := filepath.Join(dir, DirConfigName)
+	require.NoError(t, WriteConfigFile(path, file))
+	migrated, err := LoadConfig(LoadConfigOptions{Dir: dir})
+	require.NoError(t, err)
+
+	// Same rules, now all from the config file
+	assert.Equal(t, legacy.ExtensionsToDelete, migrated.ExtensionsToDelete)
+	assert.Equal(t, legacy.PrefixesToDelete, migrated.PrefixesToDelete)
+	assert.Equal(t, legacy.ExtensionReplacements, migrated.ExtensionReplacements)
+	assert.Equal(t, legacy.Excludes, migrated.Excludes)
+	assert.Equal(t, legacy.RenameCollisions, migrated.RenameCollisions)
+	require.Len(t, migrated.DeleteRules, 1)
+	assert.Equal(t, path, migrated.DeleteRules[0].Source)
+	assert.Equal(t, path, migrated.Sources["extension_replacements:.jpeg"])
+}
@@ -0,0 +1,615 @@
+package purge
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat is the syntax of a config file, picked by its extension
+type ConfigFormat string
+
+const (
+	FormatJSON ConfigFormat = "json" // .json, and any other extension
+	FormatYAML ConfigFormat = "yaml" // .yaml or .yml
+	FormatTOML ConfigFormat = "toml" // .toml
+)
+
+// configExtensions are the extensions of the config files looked for, in
+// the order they are tried
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// ConfigFormatOf returns the format of the config file at path
+func ConfigFormatOf(path string) ConfigFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// ConfigError is a problem with a setting of a config file, at its line.
+// The errors of every format read the same, as they are all decoded alike.
+type ConfigError struct {
+	Path    string
+	Line    int    // 0 if unknown
+	Setting string // e.g. "delete.rules[1].kind"; empty for syntax errors
+	Err     error
+}
+
+func (e *ConfigError) Error() string {
+	loc := e.Path
+	if e.Line > 0 {
+		loc += ":" + strconv.Itoa(e.Line)
+	}
+	if e.Setting == "" {
+		return fmt.Sprintf("%s: %v", loc, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", loc, e.Setting, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// configLines maps the settings of a config file, named like
+// ConfigError.Setting, to the line they are set on
+type configLines map[string]int
+
+// set records the line of setting, unless it already has one. The whole
+// document is the setting "".
+func (l configLines) set(setting string, line int) {
+	if _, ok := l[setting]; !ok {
+		l[setting] = line
+	}
+}
+
+// line returns the line of setting, or of the closest setting holding it
+func (l configLines) line(setting string) int {
+	for {
+		if line, ok := l[setting]; ok {
+			return line
+		}
+		if setting == "" {
+			return 0
+		}
+		i := strings.LastIndexAny(setting, ".[")
+		setting = setting[:max(i, 0)]
+	}
+}
+
+func joinSetting(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func indexSetting(parent string, i int) string {
+	return fmt.Sprintf("%s[%d]", parent, i)
+}
+
+// parseConfigTree parses a config file in its format into JSON-like values
+// (maps, slices and scalars), with the line of every setting
+func parseConfigTree(data []byte, path string) (any, configLines, error) {
+	lines := make(configLines)
+	var tree any
+	var err error
+	switch ConfigFormatOf(path) {
+	case FormatYAML:
+		tree, err = parseYAMLTree(data, lines)
+	case FormatTOML:
+		tree, err = parseTOMLTree(data, lines)
+	default:
+		tree, err = parseJSONTree(data, lines)
+	}
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		configErr.Path = path
+	}
+	return tree, lines, err
+}
+
+// parseJSONTree parses a JSON document token by token, to know the line of
+// every setting
+func parseJSONTree(data []byte, lines configLines) (any, error) {
+	p := &jsonTreeParser{dec: json.NewDecoder(bytes.NewReader(data)), lines: lines}
+	p.dec.UseNumber() // numbers are decoded again into their setting
+	for i, b := range data {
+		if b == '\n' {
+			p.newlines = append(p.newlines, i)
+		}
+	}
+
+	tree, err := p.value("")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, p.syntaxError(cmp.Or(err, errors.New("unexpected data after the settings")))
+	}
+	return tree, nil
+}
+
+type jsonTreeParser struct {
+	dec      *json.Decoder
+	newlines []int // offsets of the line ends
+	lines    configLines
+}
+
+func (p *jsonTreeParser) value(setting string) (any, error) {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.syntaxError(err)
+	}
+	p.lines.set(setting, p.line())
+
+	switch tok {
+	case json.Delim('{'):
+		obj := make(map[string]any)
+		for p.dec.More() {
+			tok, err := p.dec.Token()
+			if err != nil {
+				return nil, p.syntaxError(err)
+			}
+			key := tok.(string) // the decoder checks keys are strings
+			child := joinSetting(setting, key)
+			p.lines.set(child, p.line())
+			if obj[key], err = p.value(child); err != nil {
+				return nil, err
+			}
+		}
+		_, err = p.dec.Token()
+		return obj, p.syntaxError(err)
+	case json.Delim('['):
+		list := []any{}
+		for i := 0; p.dec.More(); i++ {
+			item, err := p.value(indexSetting(setting, i))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		_, err = p.dec.Token()
+		return list, p.syntaxError(err)
+	}
+	return tok, nil
+}
+
+// line returns the line of the last token read
+func (p *jsonTreeParser) line() int {
+	return p.lineAt(p.dec.InputOffset() - 1)
+}
+
+func (p *jsonTreeParser) lineAt(offset int64) int {
+	return sort.SearchInts(p.newlines, int(offset)) + 1
+}
+
+func (p *jsonTreeParser) syntaxError(err error) error {
+	if err == nil {
+		return nil
+	}
+	offset := p.dec.InputOffset()
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	msg := strings.TrimPrefix(err.Error(), "json: ")
+	return &ConfigError{Line: p.lineAt(max(offset-1, 0)), Err: fmt.Errorf("invalid JSON: %s", msg)}
+}
+
+// yamlLineError matches the errors of yaml.v3, which only give the line in
+// the message
+var yamlLineError = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func parseYAMLTree(data []byte, lines configLines) (any, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		if m := yamlLineError.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, &ConfigError{Line: line, Err: fmt.Errorf("invalid YAML: %s", m[2])}
+		}
+		return nil, &ConfigError{Err: fmt.Errorf("invalid YAML: %s", strings.TrimPrefix(err.Error(), "yaml: "))}
+	}
+	return yamlValue(&doc, "", lines)
+}
+
+func yamlValue(n *yaml.Node, setting string, lines configLines) (any, error) {
+	lines.set(setting, n.Line)
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil // empty file
+		}
+		return yamlValue(n.Content[0], setting, lines)
+	case yaml.AliasNode:
+		return yamlValue(n.Alias, setting, lines)
+	case yaml.MappingNode:
+		obj := make(map[string]any, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, &ConfigError{Line: key.Line, Setting: setting, Err: errors.New("keys must be strings")}
+			}
+			child := joinSetting(setting, key.Value)
+			lines.set(child, key.Line)
+			v, err := yamlValue(value, child, lines)
+			if err != nil {
+				return nil, err
+			}
+			obj[key.Value] = v
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		list := make([]any, 0, len(n.Content))
+		for i, item := range n.Content {
+			v, err := yamlValue(item, indexSetting(setting, i), lines)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return nil, &ConfigError{Line: n.Line, Setting: setting, Err: fmt.Errorf("invalid YAML: %s", strings.TrimPrefix(err.Error(), "yaml: "))}
+	}
+	return v, nil
+}
+
+func parseTOMLTree(data []byte, lines configLines) (any, error) {
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &ConfigError{Line: parseErr.Position.Line, Err: fmt.Errorf("invalid TOML: %s", parseErr.Message)}
+		}
+		return nil, &ConfigError{Err: fmt.Errorf("invalid TOML: %s", strings.TrimPrefix(err.Error(), "toml: "))}
+	}
+	indexTOMLLines(data, lines)
+	return tomlValue(doc), nil
+}
+
+// tomlValue turns the arrays of tables of a decoded TOML document into
+// plain lists
+func tomlValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = tomlValue(item)
+		}
+		return v
+	case []map[string]any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = tomlValue(item)
+		}
+		return list
+	case []any:
+		for i, item := range v {
+			v[i] = tomlValue(item)
+		}
+		return v
+	}
+	return v
+}
+
+// indexTOMLLines records the lines of the tables and keys of a valid TOML
+// document, which the TOML decoder does not tell. Keys of inline tables
+// and items of arrays get the line of the key holding them.
+func indexTOMLLines(data []byte, lines configLines) {
+	arrays := make(map[string]int) // arrays of tables, with their length so far
+	table := ""
+	var value tomlValueScanner
+	for i, text := range strings.Split(string(data), "\n") {
+		line := i + 1
+		if value.open() {
+			value.scan(text) // a value spanning lines
+			continue
+		}
+
+		text = strings.TrimSpace(text)
+		switch {
+		case text == "" || text[0] == '#':
+		case text[0] == '[':
+			isArray := strings.HasPrefix(text, "[[")
+			parts, _, ok := splitTOMLKey(strings.TrimLeft(text, "["), ']')
+			if !ok {
+				continue
+			}
+			table = ""
+			for j, part := range parts {
+				table = joinSetting(table, part)
+				if isArray && j == len(parts)-1 {
+					lines.set(table, line)
+					arrays[table]++
+				}
+				if n, ok := arrays[table]; ok {
+					table = indexSetting(table, n-1)
+				}
+			}
+			lines.set(table, line)
+		default:
+			parts, rest, ok := splitTOMLKey(text, '=')
+			if !ok {
+				continue
+			}
+			key := table
+			for _, part := range parts {
+				key = joinSetting(key, part)
+				lines.set(key, line)
+			}
+			value.scan(rest)
+		}
+	}
+}
+
+// splitTOMLKey splits the dotted key at the start of s, up to end, into its
+// parts, and returns what follows end
+func splitTOMLKey(s string, end byte) (parts []string, rest string, ok bool) {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return nil, "", false
+		}
+		switch s[0] {
+		case '"':
+			j := 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, "", false
+			}
+			part, err := strconv.Unquote(s[:j+1])
+			if err != nil {
+				return nil, "", false
+			}
+			parts, s = append(parts, part), s[j+1:]
+		case '\'':
+			j := strings.IndexByte(s[1:], '\'')
+			if j < 0 {
+				return nil, "", false
+			}
+			parts, s = append(parts, s[1:j+1]), s[j+2:]
+		default:
+			j := strings.IndexFunc(s, func(r rune) bool {
+				return !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+			})
+			if j <= 0 {
+				return nil, "", false
+			}
+			parts, s = append(parts, s[:j]), s[j:]
+		}
+
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case s == "":
+			return nil, "", false
+		case s[0] == '.':
+			s = s[1:]
+		case s[0] == end:
+			return parts, s[1:], true
+		default:
+			return nil, "", false
+		}
+	}
+}
+
+// tomlValueScanner follows a TOML value over its lines, to tell when it
+// ends: arrays and inline tables nest, and multi-line strings may hold
+// anything
+type tomlValueScanner struct {
+	depth int    // open arrays and inline tables
+	multi string // closing quotes of an open multi-line string
+}
+
+func (s *tomlValueScanner) open() bool {
+	return s.depth > 0 || s.multi != ""
+}
+
+func (s *tomlValueScanner) scan(text string) {
+	for i := 0; i < len(text); i++ {
+		if s.multi != "" {
+			switch {
+			case strings.HasPrefix(text[i:], s.multi):
+				i += len(s.multi) - 1
+				s.multi = ""
+			case text[i] == '\\' && s.multi == `"""`:
+				i++
+			}
+			continue
+		}
+
+		switch c := text[i]; {
+		case c == '#':
+			return
+		case strings.HasPrefix(text[i:], `"""`), strings.HasPrefix(text[i:], `'''`):
+			s.multi = text[i : i+3]
+			i += 2
+		case c == '"':
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case c == '\'':
+			if j := strings.IndexByte(text[i+1:], '\''); j >= 0 {
+				i += j + 1
+			}
+		case c == '[' || c == '{':
+			s.depth++
+		case c == ']' || c == '}':
+			s.depth--
+		}
+	}
+}
+
+// configDecoder decodes a parsed config tree into a value the way
+// encoding/json would, rejecting unknown fields, and tells the line of any
+// setting it cannot use
+type configDecoder struct {
+	path  string
+	lines configLines
+}
+
+func (d *configDecoder) errorAt(setting string, err error) error {
+	return &ConfigError{Path: d.path, Line: d.lines.line(setting), Setting: setting, Err: err}
+}
+
+// decode decodes v into rv. Structs are decoded field by field, so an error
+// is reported at the setting it is about; anything else is left to its
+// JSON decoding.
+func (d *configDecoder) decode(v any, rv reflect.Value, setting string) error {
+	_, custom := rv.Addr().Interface().(json.Unmarshaler)
+	switch {
+	case v == nil || custom:
+	case rv.Kind() == reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return d.errorAt(setting, fmt.Errorf("expected a table of settings, got %s", describeValue(v)))
+		}
+		fields := jsonFields(rv)
+		for _, key := range d.keysInOrder(obj, setting) {
+			field, ok := fields[key]
+			if !ok {
+				return d.errorAt(joinSetting(setting, key), fmt.Errorf("unknown field %q", key))
+			}
+			if err := d.decode(obj[key], field, joinSetting(setting, key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Struct:
+		list, ok := v.([]any)
+		if !ok {
+			return d.errorAt(setting, fmt.Errorf("expected a list, got %s", describeValue(v)))
+		}
+		items := reflect.MakeSlice(rv.Type(), len(list), len(list))
+		for i, item := range list {
+			if err := d.decode(item, items.Index(i), indexSetting(setting, i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(items)
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return d.errorAt(setting, err)
+	}
+	if err := decodeStrict(data, rv.Addr().Interface()); err != nil {
+		return d.errorAt(setting, valueError(err))
+	}
+	return nil
+}
+
+// keysInOrder returns the keys of obj in the order they are set in the
+// file, so the first problem is reported first
+func (d *configDecoder) keysInOrder(obj map[string]any, setting string) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if la, lb := d.lines.line(joinSetting(setting, a)), d.lines.line(joinSetting(setting, b)); la != lb {
+			return la - lb
+		}
+		return strings.Compare(a, b)
+	})
+	return keys
+}
+
+// jsonFields returns the fields of a struct by their JSON name, with the
+// fields of embedded structs promoted
+func jsonFields(rv reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case !f.IsExported() || name == "-":
+		case f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct:
+			for key, field := range jsonFields(rv.Field(i)) {
+				fields[key] = field
+			}
+		case name == "":
+			fields[f.Name] = rv.Field(i)
+		default:
+			fields[name] = rv.Field(i)
+		}
+	}
+	return fields
+}
+
+// valueError rewords an error of encoding/json about a single setting, as
+// the Go types it names mean nothing in a config file
+func valueError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("expected %s, got %s", describeType(typeErr.Type), typeErr.Value)
+	}
+	return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "a table"
+	case reflect.Pointer:
+		return describeType(t.Elem())
+	}
+	return t.String()
+}
+
+func describeValue(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "a table"
+	case []any:
+		return "a list"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case time.Time:
+		return "a date"
+	}
+	return "a number"
+}
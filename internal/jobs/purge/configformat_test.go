@@ -0,0 +1,272 @@
+package purge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The same config in every format
+var configDocuments = map[string]string{
+	".json": `{
+  "version": 1,
+  "delete": {
+    "extensions": {"add": [".tmp"]},
+    "prefixes": ["._"],
+    "rules": [
+      {"id": "dumps", "kind": "regex", "pattern": "core\\.[0-9]+"},
+      {"kind": "glob", "pattern": "**/*.bak", "min_size": "1MiB", "mtime_older_than": "30d"}
+    ]
+  },
+  "rename": {"extension_replacements": {".jpeg": ".jpg"}, "collisions": "suffix"},
+  "excludes": [".git/"],
+  "safety": {"fingerprint_hash": true, "max_changes": 100, "max_delete_bytes": "1GiB"},
+  "logging": {"debug": true}
+}`,
+	".yaml": `# Shared cleanup settings
+version: 1
+delete:
+  extensions:
+    add: [.tmp]
+  prefixes: ["._"]
+  rules:
+    - id: dumps
+      kind: regex
+      pattern: 'core\.[0-9]+'
+    - kind: glob
+      pattern: "**/*.bak"
+      min_size: 1MiB
+      mtime_older_than: 30d
+rename:
+  extension_replacements:
+    .jpeg: .jpg
+  collisions: suffix
+excludes: [.git/]
+safety:
+  fingerprint_hash: true
+  max_changes: 100
+  max_delete_bytes: 1GiB
+logging:
+  debug: true
+`,
+	".toml": `# Shared cleanup settings
+version = 1
+excludes = [".git/"]
+
+[delete]
+extensions = { add = [".tmp"] }
+prefixes = ["._"]
+
+[[delete.rules]]
+id = "dumps"
+kind = "regex"
+pattern = 'core\.[0-9]+'
+
+[[delete.rules]]
+kind = "glob"
+pattern = "**/*.bak"
+min_size = "1MiB"
+mtime_older_than = "30d"
+
+[rename]
+collisions = "suffix"
+
+[rename.extension_replacements]
+".jpeg" = ".jpg"
+
+[safety]
+fingerprint_hash = true
+max_changes = 100
+max_delete_bytes = "1GiB"
+
+[logging]
+debug = true
+`,
+}
+
+func TestReadConfigFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	var want *ConfigFile
+	for _, ext := range configExtensions {
+		doc, ok := configDocuments[ext]
+		if !ok {
+			continue
+		}
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(dir, "config"+ext)
+			require.NoError(t, os.WriteFile(path, []byte(doc), 0644))
+			file, err := ReadConfigFile(path)
+			require.NoError(t, err)
+
+			if want == nil {
+				want = file
+				require.Len(t, want.Delete.Rules, 2)
+				assert.Equal(t, ByteSize(1<<20), want.Delete.Rules[1].MinSize)
+				assert.Equal(t, SafetyLimits{MaxChanges: 100, MaxDeleteBytes: 1 << 30}, want.Safety.SafetyLimits)
+				return
+			}
+			assert.Equal(t, want, file)
+		})
+	}
+}
+
+func TestReadConfigFileFormatErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		data    string
+		wantErr string // after the path
+	}{
+		{
+			name:    "json unknown field",
+			ext:     ".json",
+			data:    "{\n  \"version\": 1,\n  \"delete\": {\n    \"extension\": [\".tmp\"]\n  }\n}",
+			wantErr: `:4: delete.extension: unknown field "extension"`,
+		},
+		{
+			name:    "yaml unknown field",
+			ext:     ".yaml",
+			data:    "version: 1\ndelete:\n  extension: [.tmp]\n",
+			wantErr: `:3: delete.extension: unknown field "extension"`,
+		},
+		{
+			name:    "toml unknown field",
+			ext:     ".toml",
+			data:    "version = 1\n\n[delete]\nextension = [\".tmp\"]\n",
+			wantErr: `:4: delete.extension: unknown field "extension"`,
+		},
+		{
+			name:    "json wrong type",
+			ext:     ".json",
+			data:    "{\"version\": 1,\n\"safety\": {\"max_changes\": \"many\"}}",
+			wantErr: `:2: safety.max_changes: expected a whole number, got string`,
+		},
+		{
+			name:    "yaml wrong type",
+			ext:     ".yaml",
+			data:    "version: 1\nsafety:\n  max_changes: many\n",
+			wantErr: `:3: safety.max_changes: expected a whole number, got string`,
+		},
+		{
+			name:    "toml wrong type",
+			ext:     ".toml",
+			data:    "version = 1\n[safety]\nmax_changes = \"many\"\n",
+			wantErr: `:3: safety.max_changes: expected a whole number, got string`,
+		},
+		{
+			name:    "yaml field of a later rule",
+			ext:     ".yaml",
+			data:    "version: 1\ndelete:\n  rules:\n    - kind: glob\n      pattern: '*.a'\n    - kind: glob\n      pattern: '*.b'\n      max_age: 3d\n",
+			wantErr: `:8: delete.rules[1].max_age: unknown field "max_age"`,
+		},
+		{
+			name:    "toml field of a later rule",
+			ext:     ".toml",
+			data:    "version = 1\n[[delete.rules]]\nkind = \"glob\"\npattern = \"*.a\"\n\n[[delete.rules]]\nkind = \"glob\"\npattern = \"*.b\"\nmax_age = \"3d\"\n",
+			wantErr: `:9: delete.rules[1].max_age: unknown field "max_age"`,
+		},
+		{
+			name:    "toml invalid value",
+			ext:     ".toml",
+			data:    "version = 1\n[[delete.rules]]\nkind = \"glob\"\npattern = \"\"\"\n*.a\n\"\"\"\nmin_size = \"lots\"\n",
+			wantErr: `:7: delete.rules[0].min_size: invalid size "lots"`,
+		},
+		{
+			name:    "json syntax",
+			ext:     ".json",
+			data:    "{\"version\": 1,\n\"delete\": {\n}",
+			wantErr: `:3: invalid JSON: unexpected end of JSON input`,
+		},
+		{
+			name:    "yaml syntax",
+			ext:     ".yaml",
+			data:    "version: 1\ndelete:\n\textensions: [.tmp]\n",
+			wantErr: `:3: invalid YAML: found character that cannot start any token`,
+		},
+		{
+			name:    "toml syntax",
+			ext:     ".toml",
+			data:    "version = 1\n\n[delete]\nextensions = [.tmp]\n",
+			wantErr: `:4: invalid TOML: `,
+		},
+		{
+			name:    "toml newer version",
+			ext:     ".toml",
+			data:    "# next year\nversion = 2\n",
+			wantErr: `:2: version: unsupported version 2 (want 1)`,
+		},
+		{
+			name:    "yaml not a table",
+			ext:     ".yaml",
+			data:    "- version: 1\n",
+			wantErr: `:1: expected a table of settings, got a list`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config"+tt.ext)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0644))
+			_, err := ReadConfigFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), path+tt.wantErr)
+
+			var configErr *ConfigError
+			require.ErrorAs(t, err, &configErr)
+			assert.Equal(t, path, configErr.Path)
+		})
+	}
+}
+
+func TestFindConfigFormats(t *testing.T) {
+	isolateConfig(t)
+	root := t.TempDir()
+	scan := filepath.Join(root, "scan")
+	require.NoError(t, os.MkdirAll(scan, 0755))
+	yamlPath := filepath.Join(root, ".housekeeper.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte("version: 1\ndelete:\n  extensions: [.part]\n"), 0644))
+
+	loc, err := FindConfig(LoadConfigOptions{Dir: scan})
+	require.NoError(t, err)
+	assert.Equal(t, yamlPath, loc.Path)
+	cfg, err := LoadConfig(LoadConfigOptions{Dir: scan})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".part"}, cfg.ExtensionsToDelete)
+
+	// Two formats side by side are ambiguous
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".housekeeper.toml"), []byte("version = 1\n"), 0644))
+	_, err = FindConfig(LoadConfigOptions{Dir: scan})
+	assert.ErrorContains(t, err, "keep one")
+
+	assert.Error(t, WriteConfigFile(filepath.Join(root, "out.toml"), NewConfigFile(cfg)), "only JSON is written")
+}
+
+func TestPreviewChangesLayersOtherFormats(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	files := map[string]string{
+		"yaml/.housekeeper.yml":  "version: 1\ndelete:\n  extensions:\n    add: [.o]\n",
+		"yaml/a.o":               "x",
+		"toml/.housekeeper.toml": "version = 1\n[delete.extensions]\nadd = [\".a\"]\n",
+		"toml/b.a":               "x",
+		"c.o":                    "x",
+	}
+	for name, data := range files {
+		require.NoError(t, afero.WriteFile(fsys, filepath.Join(root, name), []byte(data), 0644))
+	}
+
+	changes, err := previewChanges(context.Background(), fsys, root, &Config{}, 0, nil)
+	require.NoError(t, err)
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Target+" "+c.Reason.Source)
+	}
+	assert.ElementsMatch(t, []string{
+		filepath.Join(root, "yaml", "a.o") + " " + filepath.Join(root, "yaml", ".housekeeper.yml"),
+		filepath.Join(root, "toml", "b.a") + " " + filepath.Join(root, "toml", ".housekeeper.toml"),
+	}, got)
+}
@@ -0,0 +1,329 @@
+package purge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"housekeeper/internal/common"
+)
+
+// DeleteMode selects what happens to files planned for deletion
+type DeleteMode string
+
+const (
+	DeleteHard       DeleteMode = "hard"       // remove the file for good (default)
+	DeleteQuarantine DeleteMode = "quarantine" // move it under a quarantine dir mirroring the original tree
+	DeleteTrash      DeleteMode = "trash"      // move it into the freedesktop.org home trash
+)
+
+// QuarantineManifest is the file in the quarantine dir listing every moved file
+const QuarantineManifest = "manifest.jsonl"
+
+// ParseDeleteMode validates a mode name. An empty name means DeleteHard.
+func ParseDeleteMode(s string) (DeleteMode, error) {
+	switch mode := DeleteMode(strings.ToLower(s)); mode {
+	case "":
+		return DeleteHard, nil
+	case DeleteHard, DeleteQuarantine, DeleteTrash:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown delete mode %q (want hard, quarantine or trash)", s)
+}
+
+// deleter disposes of files planned for deletion
+type deleter interface {
+	// remove disposes of path and returns where it went, or "" if it is gone
+	remove(path string) (string, error)
+}
+
+func newDeleter(fsys afero.Fs, opts ApplyOptions) (deleter, error) {
+	mode, err := ParseDeleteMode(string(opts.DeleteMode))
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case DeleteQuarantine:
+		dir := opts.QuarantineDir
+		if dir == "" {
+			if dir, err = DefaultQuarantineDir(); err != nil {
+				return nil, err
+			}
+		}
+		return &quarantineDeleter{fsys: fsys, dir: dir}, nil
+	case DeleteTrash:
+		dir := opts.TrashDir
+		if dir == "" {
+			if dir, err = DefaultTrashDir(); err != nil {
+				return nil, err
+			}
+		}
+		return &trashDeleter{fsys: fsys, dir: dir}, nil
+	default:
+		return hardDeleter{fsys: fsys}, nil
+	}
+}
+
+// DefaultQuarantineDir returns housekeeper/quarantine under the user data dir
+func DefaultQuarantineDir() (string, error) {
+	dir, err := dataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "housekeeper", "quarantine"), nil
+}
+
+// DefaultTrashDir returns the freedesktop.org home trash, $XDG_DATA_HOME/Trash
+func DefaultTrashDir() (string, error) {
+	dir, err := dataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "Trash"), nil
+}
+
+// dataHome returns $XDG_DATA_HOME, falling back to ~/.local/share, or
+// %LOCALAPPDATA% on Windows
+func dataHome() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("LOCALAPPDATA"); runtime.GOOS == "windows" && dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locating data dir: %w", err)
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+type hardDeleter struct {
+	fsys afero.Fs
+}
+
+func (h hardDeleter) remove(path string) (string, error) {
+	return "", h.fsys.Remove(path)
+}
+
+// QuarantineEntry is one line of the quarantine manifest
+type QuarantineEntry struct {
+	Original    string      `json:"original"`
+	Quarantined string      `json:"quarantined"`
+	Size        int64       `json:"size"`
+	Mode        fs.FileMode `json:"mode"`
+	DeletedAt   time.Time   `json:"deleted_at"`
+}
+
+type quarantineDeleter struct {
+	fsys afero.Fs
+	dir  string
+	mu   sync.Mutex // guards the manifest
+}
+
+func (q *quarantineDeleter) remove(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := q.fsys.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+
+	dest, err := uniquePath(q.fsys, filepath.Join(q.dir, mirrorPath(abs)))
+	if err != nil {
+		return "", err
+	}
+	if err := q.fsys.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	if err := moveFile(q.fsys, abs, dest); err != nil {
+		return "", err
+	}
+
+	entry := QuarantineEntry{
+		Original:    abs,
+		Quarantined: dest,
+		Size:        info.Size(),
+		Mode:        info.Mode(),
+		DeletedAt:   timeNow(),
+	}
+	q.mu.Lock()
+	err = appendJSONLine(q.fsys, filepath.Join(q.dir, QuarantineManifest), entry)
+	q.mu.Unlock()
+	if err != nil {
+		// Put the file back so the change fails as a whole; if that fails
+		// too, the file is in quarantine and the journal has to record it
+		if moveErr := moveFile(q.fsys, dest, abs); moveErr != nil {
+			common.Warn.Printf("Quarantined %s → %s without a manifest entry: %v", abs, dest, err)
+			return dest, nil
+		}
+		return "", fmt.Errorf("updating quarantine manifest: %w", err)
+	}
+	return dest, nil
+}
+
+// mirrorPath turns an absolute path into a relative one that keeps the full
+// tree, e.g. C:\data\a.tmp becomes C\data\a.tmp
+func mirrorPath(abs string) string {
+	vol := filepath.VolumeName(abs)
+	rest := strings.TrimLeft(abs[len(vol):], `\/`)
+	vol = strings.Trim(strings.ReplaceAll(vol, ":", ""), `\/`)
+	return filepath.Join(vol, rest)
+}
+
+type trashDeleter struct {
+	fsys afero.Fs
+	dir  string
+}
+
+// remove follows the freedesktop.org trash spec: the .trashinfo file is
+// created first to reserve a unique name, then the file is moved to files/
+func (t *trashDeleter) remove(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	filesDir := filepath.Join(t.dir, "files")
+	infoDir := filepath.Join(t.dir, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := t.fsys.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: filepath.ToSlash(abs)}).EscapedPath(),
+		timeNow().Format("2006-01-02T15:04:05"))
+
+	name := filepath.Base(abs)
+	for i := 1; ; i++ {
+		candidate := name
+		if i > 1 {
+			candidate = fmt.Sprintf("%s.%d", name, i)
+		}
+
+		infoPath := filepath.Join(infoDir, candidate+".trashinfo")
+		f, err := t.fsys.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		dest := filepath.Join(filesDir, candidate)
+		if exists, _ := afero.Exists(t.fsys, dest); exists {
+			f.Close()
+			t.fsys.Remove(infoPath)
+			continue
+		}
+
+		_, err = f.WriteString(info)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = moveFile(t.fsys, abs, dest)
+		}
+		if err != nil {
+			t.fsys.Remove(infoPath)
+			return "", err
+		}
+		return dest, nil
+	}
+}
+
+// uniquePath returns p, or p with a numeric suffix if p already exists
+func uniquePath(fsys afero.Fs, p string) (string, error) {
+	candidate := p
+	for i := 1; ; i++ {
+		exists, err := afero.Exists(fsys, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s.%d", p, i)
+	}
+}
+
+// moveFile renames src to dst, falling back to copy and delete when the
+// rename fails, e.g. across devices
+func moveFile(fsys afero.Fs, src, dst string) error {
+	renameErr := fsys.Rename(src, dst)
+	if renameErr == nil {
+		return nil
+	}
+
+	if err := copyFile(fsys, src, dst); err != nil {
+		return errors.Join(renameErr, err)
+	}
+	return fsys.Remove(src)
+}
+
+// copyFile copies src to a new file dst, removing dst again on failure
+func copyFile(fsys afero.Fs, src, dst string) (err error) {
+	info, err := fsys.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	in, err := fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := fsys.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			fsys.Remove(dst)
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return fsys.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// appendJSONLine appends v as a single JSON line to the file at path
+func appendJSONLine(fsys afero.Fs, path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
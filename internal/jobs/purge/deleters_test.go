@@ -0,0 +1,190 @@
+package purge
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useMemFs points AppFs at a fresh in-memory filesystem for the test
+func useMemFs(t *testing.T) afero.Fs {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	original := AppFs
+	AppFs = fs
+	t.Cleanup(func() { AppFs = original })
+	return fs
+}
+
+func TestParseDeleteMode(t *testing.T) {
+	for in, want := range map[string]DeleteMode{
+		"":           DeleteHard,
+		"hard":       DeleteHard,
+		"Quarantine": DeleteQuarantine,
+		"trash":      DeleteTrash,
+	} {
+		got, err := ParseDeleteMode(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := ParseDeleteMode("shred")
+	assert.Error(t, err)
+}
+
+func TestQuarantineDeleter(t *testing.T) {
+	fs := useMemFs(t)
+	src := filepath.Join(string(filepath.Separator), "data", "photos", "a.tmp")
+	qdir := filepath.Join(string(filepath.Separator), "quarantine")
+	require.NoError(t, afero.WriteFile(fs, src, []byte("junk"), 0640))
+
+	d := &quarantineDeleter{fsys: fs, dir: qdir}
+	dest, err := d.remove(src)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(qdir, "data", "photos", "a.tmp"), dest)
+	exists, _ := afero.Exists(fs, src)
+	assert.False(t, exists, "original should be gone")
+	data, err := afero.ReadFile(fs, dest)
+	require.NoError(t, err)
+	assert.Equal(t, "junk", string(data))
+
+	// A second file at the same path must not overwrite the first
+	require.NoError(t, afero.WriteFile(fs, src, []byte("more junk"), 0640))
+	dest2, err := d.remove(src)
+	require.NoError(t, err)
+	assert.Equal(t, dest+".1", dest2)
+
+	manifest, err := fs.Open(filepath.Join(qdir, QuarantineManifest))
+	require.NoError(t, err)
+	defer manifest.Close()
+
+	var entries []QuarantineEntry
+	scanner := bufio.NewScanner(manifest)
+	for scanner.Scan() {
+		var e QuarantineEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, e)
+	}
+	require.Len(t, entries, 2)
+	assert.Equal(t, src, entries[0].Original)
+	assert.Equal(t, dest, entries[0].Quarantined)
+	assert.Equal(t, int64(4), entries[0].Size)
+	assert.Equal(t, dest2, entries[1].Quarantined)
+}
+
+// manifestFailFs fails to open the quarantine manifest, and to write
+// anything under blocked
+type manifestFailFs struct {
+	afero.Fs
+	blocked string
+}
+
+func (f manifestFailFs) OpenFile(name string, flag int, perm fs.FileMode) (afero.File, error) {
+	if filepath.Base(name) == QuarantineManifest || f.isBlocked(name) && flag&os.O_CREATE != 0 {
+		return nil, errors.New("disk full")
+	}
+	return f.Fs.OpenFile(name, flag, perm)
+}
+
+func (f manifestFailFs) Rename(oldname, newname string) error {
+	if f.isBlocked(newname) {
+		return errors.New("read-only")
+	}
+	return f.Fs.Rename(oldname, newname)
+}
+
+func (f manifestFailFs) isBlocked(name string) bool {
+	return f.blocked != "" && strings.HasPrefix(name, f.blocked)
+}
+
+func TestQuarantineDeleterManifestFailure(t *testing.T) {
+	src := filepath.Join(string(filepath.Separator), "data", "a.tmp")
+	qdir := filepath.Join(string(filepath.Separator), "quarantine")
+
+	// The move is rolled back and the change fails
+	mem := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(mem, src, []byte("junk"), 0640))
+	d := &quarantineDeleter{fsys: manifestFailFs{Fs: mem}, dir: qdir}
+	dest, err := d.remove(src)
+	assert.ErrorContains(t, err, "disk full")
+	assert.Empty(t, dest)
+	exists, _ := afero.Exists(mem, src)
+	assert.True(t, exists, "the file should be back in place")
+
+	// If the file cannot be moved back, the move stands and is reported
+	mem = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(mem, src, []byte("junk"), 0640))
+	d = &quarantineDeleter{fsys: manifestFailFs{Fs: mem, blocked: filepath.Dir(src)}, dir: qdir}
+	dest, err = d.remove(src)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(qdir, "data", "a.tmp"), dest)
+	exists, _ = afero.Exists(mem, dest)
+	assert.True(t, exists)
+}
+
+func TestTrashDeleter(t *testing.T) {
+	fs := useMemFs(t)
+	originalNow := timeNow
+	timeNow = func() time.Time { return time.Date(2024, 8, 31, 22, 32, 8, 0, time.Local) }
+	t.Cleanup(func() { timeNow = originalNow })
+
+	src := filepath.Join(string(filepath.Separator), "home", "me", "my file.txt")
+	trash := filepath.Join(string(filepath.Separator), "trash")
+	require.NoError(t, afero.WriteFile(fs, src, []byte("x"), 0644))
+
+	d := &trashDeleter{fsys: fs, dir: trash}
+	dest, err := d.remove(src)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(trash, "files", "my file.txt"), dest)
+
+	info, err := afero.ReadFile(fs, filepath.Join(trash, "info", "my file.txt.trashinfo"))
+	require.NoError(t, err)
+	assert.Equal(t, "[Trash Info]\nPath="+strings.ReplaceAll(filepath.ToSlash(src), " ", "%20")+
+		"\nDeletionDate=2024-08-31T22:32:08\n", string(info))
+
+	// Same name again gets a new, unique trash name
+	require.NoError(t, afero.WriteFile(fs, src, []byte("y"), 0644))
+	dest2, err := d.remove(src)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(trash, "files", "my file.txt.2"), dest2)
+	exists, _ := afero.Exists(fs, filepath.Join(trash, "info", "my file.txt.2.trashinfo"))
+	assert.True(t, exists)
+}
+
+func TestApplyAllWithOptionsQuarantine(t *testing.T) {
+	fs := useMemFs(t)
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	file := filepath.Join(string(filepath.Separator), "scan", "old.tmp")
+	qdir := filepath.Join(string(filepath.Separator), "q")
+	require.NoError(t, afero.WriteFile(fs, file, []byte("x"), 0644))
+
+	results, err := ApplyAllWithOptions([]Change{{Type: DeleteFile, Target: file}}, ApplyOptions{
+		DeleteMode:    DeleteQuarantine,
+		QuarantineDir: qdir,
+	})
+	applied := results.Applied()
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+
+	exists, _ := afero.Exists(fs, filepath.Join(qdir, "scan", "old.tmp"))
+	assert.True(t, exists, "file should be quarantined")
+}
+
+func TestMirrorPath(t *testing.T) {
+	abs := filepath.Join(string(filepath.Separator), "var", "data", "a.tmp")
+	assert.Equal(t, filepath.Join("var", "data", "a.tmp"), mirrorPath(abs))
+}
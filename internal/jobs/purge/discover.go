@@ -0,0 +1,149 @@
+package purge
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// ConfigEnv names the config file to use when none is given explicitly
+const ConfigEnv = "HOUSEKEEPER_CONFIG"
+
+// DirConfigName is the config file looked for in the scanned dir and its
+// ancestors. Below the scanned dir, it overrides the config for its subtree.
+// It can also be YAML or TOML, with the extension changed to match.
+const DirConfigName = ".housekeeper.json"
+
+// dirConfigBase is DirConfigName without its extension
+const dirConfigBase = ".housekeeper"
+
+// BuiltinSource is the source recorded for the settings of DefaultConfig
+const BuiltinSource = "built-in defaults"
+
+// ConfigLocation is the config file FindConfig picked and why
+type ConfigLocation struct {
+	Path  string `json:"path,omitempty"` // empty for the built-in defaults
+	Found string `json:"found"`          // how the file was found
+}
+
+// FindConfig returns the config file to use, the first of:
+//   - opts.Path, given explicitly
+//   - the file named by $HOUSEKEEPER_CONFIG
+//   - .housekeeper.json in opts.Dir or the closest of its ancestors
+//   - housekeeper/config.json under $XDG_CONFIG_HOME
+//
+// and the built-in defaults if none exists. Explicit paths must exist. The
+// files looked for can also be .yaml, .yml or .toml, but only one of them
+// in the same dir.
+func FindConfig(opts LoadConfigOptions) (ConfigLocation, error) {
+	if opts.Path != "" {
+		return ConfigLocation{Path: opts.Path, Found: "given explicitly"}, nil
+	}
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return ConfigLocation{Path: path, Found: "from $" + ConfigEnv}, nil
+	}
+
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return ConfigLocation{}, err
+	}
+	osFs := afero.NewOsFs()
+	for {
+		if path, err := findConfigFile(osFs, dir, dirConfigBase); err != nil {
+			return ConfigLocation{}, err
+		} else if path != "" {
+			return ConfigLocation{Path: path, Found: "in the scanned dir or an ancestor"}, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	if home, err := configHome(); err == nil {
+		if path, err := findConfigFile(osFs, filepath.Join(home, "housekeeper"), "config"); err != nil {
+			return ConfigLocation{}, err
+		} else if path != "" {
+			return ConfigLocation{Path: path, Found: "in the user config dir"}, nil
+		}
+	}
+	return ConfigLocation{Found: "no config file found"}, nil
+}
+
+// findConfigFile returns the config file named base in dir, with any of
+// the config extensions, or "" if there is none. More than one is an error,
+// as is failing to check for one, so an unreadable config is not skipped.
+func findConfigFile(fsys afero.Fs, dir, base string) (string, error) {
+	var found string
+	for _, ext := range configExtensions {
+		path := filepath.Join(dir, base+ext)
+		info, err := fsys.Stat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return "", fmt.Errorf("checking config %s: %w", path, err)
+		case info.IsDir():
+			continue
+		case found != "":
+			return "", fmt.Errorf("both %s and %s are config files; keep one", found, path)
+		}
+		found = path
+	}
+	return found, nil
+}
+
+// isDirConfigName reports whether name is DirConfigName, in any format
+func isDirConfigName(name string) bool {
+	ext, ok := strings.CutPrefix(name, dirConfigBase)
+	return ok && slices.Contains(configExtensions, ext)
+}
+
+// configHome returns $XDG_CONFIG_HOME, falling back to ~/.config, or
+// %APPDATA% on Windows
+func configHome() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("APPDATA"); runtime.GOOS == "windows" && dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config dir: %w", err)
+	}
+	return filepath.Join(home, ".config"), nil
+}
+
+func (opts LoadConfigOptions) hasLegacyFiles() bool {
+	return opts.DeleteConfigPath != "" || opts.ReplaceConfigPath != "" || opts.RulesConfigPath != "" || opts.SettingsPath != ""
+}
+
+// DefaultConfig returns the built-in settings, used when there is no
+// config file
+func DefaultConfig() *Config {
+	cfg := &Config{
+		ExtensionsToDelete: []string{".db", ".info", ".ini", ".bak", ".log", ".tmp"},
+		PrefixesToDelete:   []string{"._", ".DS_Store"},
+		ExtensionReplacements: map[string]string{
+			".htm":  ".html",
+			".jpeg": ".jpg",
+		},
+		DeleteRules: []DeleteRule{
+			{ID: "office-lock-files", Kind: RuleGlob, Pattern: "~$*.{doc,docx,xls,xlsx,ppt,pptx}"},
+			{ID: "core-dumps", Kind: RuleRegex, Pattern: `core\.[0-9]+`},
+		},
+		Excludes:         []string{".git/", "node_modules/", ".venv/"},
+		RenameCollisions: CollisionSkip,
+	}
+	stampSources(cfg, BuiltinSource)
+	return cfg
+}
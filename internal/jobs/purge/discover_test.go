@@ -0,0 +1,121 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isolateConfig keeps the environment of the machine out of FindConfig and
+// returns the user config dir it uses instead
+func isolateConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(ConfigEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", home)
+	return filepath.Join(home, "housekeeper")
+}
+
+func writeConfig(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+}
+
+func TestFindConfigOrder(t *testing.T) {
+	userDir := isolateConfig(t)
+	root := t.TempDir()
+	scanned := filepath.Join(root, "photos", "2024")
+	require.NoError(t, os.MkdirAll(scanned, 0755))
+
+	explicit := filepath.Join(root, "explicit.json")
+	fromEnv := filepath.Join(root, "env.json")
+	ancestor := filepath.Join(root, DirConfigName)
+	inDir := filepath.Join(scanned, DirConfigName)
+	user := filepath.Join(userDir, "config.json")
+
+	find := func(opts LoadConfigOptions) string {
+		t.Helper()
+		loc, err := FindConfig(opts)
+		require.NoError(t, err)
+		return loc.Path
+	}
+
+	assert.Empty(t, find(LoadConfigOptions{Dir: scanned}), "built-in defaults without any file")
+
+	writeConfig(t, user, `{"version": 1}`)
+	assert.Equal(t, user, find(LoadConfigOptions{Dir: scanned}))
+
+	writeConfig(t, ancestor, `{"version": 1}`)
+	assert.Equal(t, ancestor, find(LoadConfigOptions{Dir: scanned}))
+
+	writeConfig(t, inDir, `{"version": 1}`)
+	assert.Equal(t, inDir, find(LoadConfigOptions{Dir: scanned}), "the closest dir wins")
+
+	t.Setenv(ConfigEnv, fromEnv)
+	assert.Equal(t, fromEnv, find(LoadConfigOptions{Dir: scanned}), "used even if it does not exist")
+
+	assert.Equal(t, explicit, find(LoadConfigOptions{Path: explicit, Dir: scanned}))
+}
+
+func TestLoadConfigDiscovery(t *testing.T) {
+	isolateConfig(t)
+	root := t.TempDir()
+
+	cfg, err := LoadConfig(LoadConfigOptions{Dir: root})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig().ExtensionsToDelete, cfg.ExtensionsToDelete)
+	assert.Equal(t, BuiltinSource, cfg.Sources["extensions_to_delete:.tmp"])
+
+	path := filepath.Join(root, DirConfigName)
+	writeConfig(t, path, `{"version": 1, "delete": {"extensions": [".log"]}}`)
+	cfg, err = LoadConfig(LoadConfigOptions{Dir: root})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".log"}, cfg.ExtensionsToDelete, "a config file replaces the defaults")
+	assert.Empty(t, cfg.ExtensionReplacements)
+
+	// Legacy files given explicitly apply over the config file...
+	writeLegacyLayout(t, root)
+	repls := filepath.Join(root, "userconfigs", "extension_replacements.json")
+	cfg, err = LoadConfig(LoadConfigOptions{Dir: root, ReplaceConfigPath: repls})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".log"}, cfg.ExtensionsToDelete)
+	assert.Equal(t, map[string]string{".jpeg": ".jpg"}, cfg.ExtensionReplacements)
+	assert.Equal(t, repls, cfg.Sources["extension_replacements:.jpeg"])
+
+	// ...and replace the defaults without one
+	require.NoError(t, os.Remove(path))
+	cfg, err = LoadConfig(LoadConfigOptions{Dir: root, ReplaceConfigPath: repls})
+	require.NoError(t, err)
+	assert.Empty(t, cfg.ExtensionsToDelete)
+
+	t.Setenv(ConfigEnv, filepath.Join(root, "missing.json"))
+	_, err = LoadConfig(LoadConfigOptions{Dir: root})
+	assert.Error(t, err, "a config file named by the environment must exist")
+}
+
+func TestConfigOrigins(t *testing.T) {
+	cfg := DefaultConfig()
+	path := "/home/user/.housekeeper.json"
+	file := &ConfigFile{
+		Version: ConfigVersion,
+		Delete:  DeleteSettings{Extensions: &StringList{Add: []string{".part"}}},
+		Safety:  SafetySettings{SafetyLimits: SafetyLimits{MaxChanges: 10}},
+	}
+	mergeConfig(cfg, file.Config(path))
+
+	origins := make(map[string]string)
+	for _, o := range cfg.Origins() {
+		origins[o.Setting+"="+o.Value] = o.Source
+	}
+	assert.Equal(t, BuiltinSource, origins["delete.extensions=.tmp"])
+	assert.Equal(t, path, origins["delete.extensions=.part"])
+	assert.Equal(t, BuiltinSource, origins["rename.extension_replacements=.jpeg → .jpg"])
+	assert.Equal(t, BuiltinSource, origins["excludes=.git/"])
+	assert.Equal(t, BuiltinSource, origins["rename.collisions=skip"])
+	assert.Equal(t, path, origins["safety.max_changes=10"])
+	assert.Equal(t, BuiltinSource, origins[`delete.rules=core-dumps: regex "core\\.[0-9]+"`])
+}
@@ -1,50 +1,14 @@
 package purge
 
 import (
-	"io/fs"
+    "context"
     "os"
     "path/filepath"
     "sort"
     "strings"
-)
-
-func buildDirTreeMap(root string) (map[string]map[string]bool, error) {
-    dirContents := make(map[string]map[string]bool)
-
-    err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-        if err != nil {
-            return err
-        }
-
-        if path == root {
-            dirContents[root] = make(map[string]bool)
-            return nil
-        }
-
-        parent := filepath.Dir(path)
-        if _, exists := dirContents[parent]; !exists {
-            dirContents[parent] = make(map[string]bool)
-        }
-        dirContents[parent][path] = d.IsDir()
-
-        if d.IsDir() && !dirExists(path, dirContents) {
-            dirContents[path] = make(map[string]bool)
-        }
-
-        return nil
-    })
 
-    if err != nil {
-        return nil, err
-    }
-
-    return dirContents, nil
-}
-
-func dirExists(path string, dirContents map[string]map[string]bool) bool {
-    _, ok := dirContents[path]
-    return ok
-}
+    "github.com/spf13/afero"
+)
 
 func simulateDeletions(changes []Change, dirContents map[string]map[string]bool) {
     for _, c := range changes {
@@ -72,9 +36,15 @@ func detectEmptyDirs(dirContents map[string]map[string]bool) []Change {
         dirs = append(dirs, dir)
     }
 
+    // Deepest first so emptied children make their parents empty; ties by
+    // path keep the output deterministic
     sort.Slice(dirs, func(i, j int) bool {
-        return strings.Count(dirs[i], string(os.PathSeparator)) >
-            strings.Count(dirs[j], string(os.PathSeparator))
+        di := strings.Count(dirs[i], string(os.PathSeparator))
+        dj := strings.Count(dirs[j], string(os.PathSeparator))
+        if di != dj {
+            return di > dj
+        }
+        return dirs[i] < dirs[j]
     })
 
     for _, dir := range dirs {
@@ -87,6 +57,7 @@ func detectEmptyDirs(dirContents map[string]map[string]bool) []Change {
             emptyDirs = append(emptyDirs, Change{
                 Type:   RemoveDir,
                 Target: dir,
+                Reason: &Reason{RuleID: string(RuleEmptyDir), Kind: RuleEmptyDir},
             })
             processed[dir] = true
 
@@ -100,19 +71,18 @@ func detectEmptyDirs(dirContents map[string]map[string]bool) []Change {
     return emptyDirs
 }
 
-func findEmptyDirs(root string, changes []Change) ([]Change, error) {
-    root, err := filepath.Abs(root)
+// findEmptyDirs walks root and returns the directories left empty once
+// changes are applied
+func findEmptyDirs(fsys afero.Fs, root string, changes []Change, excludes []string) ([]Change, error) {
+    ex, err := newExcluder(fsys, root, excludes)
     if err != nil {
         return nil, err
     }
 
-    dirContents, err := buildDirTreeMap(root)
+    tree, err := walkDirs(context.Background(), fsys, root, ex, 0, nil, nil)
     if err != nil {
         return nil, err
     }
 
-    simulateDeletions(changes, dirContents)
-    emptyDirs := detectEmptyDirs(dirContents)
-
-    return emptyDirs, nil
-}
\ No newline at end of file
+    return tree.emptyDirs(changes)
+}
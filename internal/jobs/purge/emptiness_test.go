@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+
+	"github.com/spf13/afero"
 )
 
 // setupEmptyDir creates a single empty directory for testing.
@@ -167,7 +169,7 @@ func TestFindEmptyDirs(t *testing.T) {
 			}
 
 			// Run findEmptyDirs
-			changes, err := findEmptyDirs(testDir, absChanges)
+			changes, err := findEmptyDirs(afero.NewOsFs(), testDir, absChanges, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("findEmptyDirs() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -213,7 +215,7 @@ func TestFindEmptyDirsWithInaccessible(t *testing.T) {
 	}
 
 	// Run findEmptyDirs
-	result, err := findEmptyDirs(testDir, changes)
+	result, err := findEmptyDirs(afero.NewOsFs(), testDir, changes, nil)
 	if err != nil {
 		t.Errorf("findEmptyDirs() error = %v, want nil", err)
 	}
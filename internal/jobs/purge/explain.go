@@ -0,0 +1,82 @@
+package purge
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Explanation tells what planning would do with a single file and why
+type Explanation struct {
+	Path       string  `json:"path"`
+	ExcludedBy string  `json:"excluded_by,omitempty"` // the excluded path, the file or one of its dirs
+	Change     *Change `json:"change,omitempty"`      // nil if the file is excluded or no rule matches
+}
+
+// Explain tells what planning the job's dir would do with path, a file
+// under it, without walking the tree. Only the ignore files of the dirs
+// leading to path are read, so rename collisions are not resolved.
+func (j *Job) Explain(path string) (*Explanation, error) {
+	p, err := newPlanner(j.fs(), j.Dir, j.Cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := relUnder(j.Dir, path)
+	if err != nil {
+		return nil, err
+	}
+	path = filepath.Join(j.Dir, rel)
+	info, err := p.fsys.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory; only files are planned", path)
+	}
+
+	// Enter every dir down to path like the walk does, checking each on the way
+	e := &Explanation{Path: path}
+	dir := j.Dir
+	p.ex.enterDir(dir)
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		next := filepath.Join(dir, name)
+		isDir := next != path
+		if p.ex.excluded(next, isDir) {
+			e.ExcludedBy = next
+			return e, nil
+		}
+		if isDir {
+			p.ex.enterDir(next)
+		}
+		dir = next
+	}
+
+	if info.Name() != IgnoreFileName && !isDirConfigName(info.Name()) {
+		e.Change = p.visit(path, info)
+	}
+	if err := p.layers.error(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// relUnder returns path relative to root, failing if it is not inside root
+func relUnder(root, path string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not inside %s", path, root)
+	}
+	return rel, nil
+}
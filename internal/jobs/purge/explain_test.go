@@ -0,0 +1,72 @@
+package purge
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobExplain(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	for _, name := range []string{"a.tmp", "photo.JPG", "notes.txt", "cache/b.tmp", "src/vendor/c.tmp", "src/d.tmp"} {
+		require.NoError(t, afero.WriteFile(fsys, filepath.Join(root, name), []byte("x"), 0644))
+	}
+	require.NoError(t, afero.WriteFile(fsys, filepath.Join(root, "src", IgnoreFileName), []byte("vendor/\n"), 0644))
+
+	job := &Job{
+		Dir: root,
+		Cfg: &Config{ExtensionsToDelete: []string{".tmp"}, Excludes: []string{"cache/"}},
+		Fs:  fsys,
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantType   ChangeType
+		wantRule   string
+		excludedBy string
+	}{
+		{name: "deleted by a rule", path: "a.tmp", wantType: DeleteFile, wantRule: "extensions_to_delete:.tmp"},
+		{name: "renamed", path: "photo.JPG", wantType: RenameFile, wantRule: "lowercase_extension"},
+		{name: "no rule matches", path: "notes.txt"},
+		{name: "excluded by the config", path: "cache/b.tmp", excludedBy: "cache"},
+		{name: "excluded by an ignore file", path: "src/vendor/c.tmp", excludedBy: "src/vendor"},
+		{name: "next to an ignore file", path: "src/d.tmp", wantType: DeleteFile, wantRule: "extensions_to_delete:.tmp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := job.Explain(filepath.Join(root, tt.path))
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(root, tt.path), e.Path)
+
+			if tt.excludedBy != "" {
+				assert.Equal(t, filepath.Join(root, tt.excludedBy), e.ExcludedBy)
+				assert.Nil(t, e.Change)
+				return
+			}
+			assert.Empty(t, e.ExcludedBy)
+			if tt.wantType == "" {
+				assert.Nil(t, e.Change)
+				return
+			}
+			require.NotNil(t, e.Change)
+			assert.Equal(t, tt.wantType, e.Change.Type)
+			assert.Equal(t, tt.wantRule, e.Change.Reason.RuleID)
+		})
+	}
+}
+
+func TestJobExplainErrors(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fsys, "/scan/dir/a.tmp", nil, 0644))
+	job := &Job{Dir: "/scan", Cfg: &Config{}, Fs: fsys}
+
+	for _, path := range []string{"/scan", "/elsewhere/a.tmp", "/scan/dir", "/scan/missing.tmp"} {
+		_, err := job.Explain(path)
+		assert.Error(t, err, path)
+	}
+}
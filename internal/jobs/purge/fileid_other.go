@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package purge
+
+import "io/fs"
+
+// fileID is unavailable on platforms without inode numbers in fs.FileInfo
+func fileID(info fs.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package purge
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID returns the inode number of info, or 0 when it is unavailable
+func fileID(info fs.FileInfo) uint64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return uint64(st.Ino)
+}
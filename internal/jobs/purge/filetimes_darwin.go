@@ -0,0 +1,18 @@
+package purge
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// fileTimes returns the access and status change times of info, falling
+// back to the modification time when the platform data is unavailable
+func fileTimes(info fs.FileInfo) (atime, ctime time.Time) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime(), info.ModTime()
+	}
+	return time.Unix(st.Atimespec.Sec, st.Atimespec.Nsec),
+		time.Unix(st.Ctimespec.Sec, st.Ctimespec.Nsec)
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package purge
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fileTimes falls back to the modification time on platforms without
+// dedicated support
+func fileTimes(info fs.FileInfo) (atime, ctime time.Time) {
+	return info.ModTime(), info.ModTime()
+}
@@ -0,0 +1,18 @@
+package purge
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// fileTimes returns the access and creation times of info. Windows has no
+// status change time, so creation time stands in for ctime.
+func fileTimes(info fs.FileInfo) (atime, ctime time.Time) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime(), info.ModTime()
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds()),
+		time.Unix(0, data.CreationTime.Nanoseconds())
+}
@@ -0,0 +1,113 @@
+package purge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Fingerprint records the state of a change target when it was planned, so
+// apply can tell whether the file changed in the meantime
+type Fingerprint struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Inode   uint64    `json:"inode,omitempty"`
+	Hash    string    `json:"hash,omitempty"` // sha256 of the content, if requested
+}
+
+// StaleError reports a change whose target no longer matches its fingerprint
+type StaleError struct {
+	Change Change
+	Detail string
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("%s changed since it was planned: %s", e.Change.Target, e.Detail)
+}
+
+// newFingerprint captures the state of the file at path described by info
+func newFingerprint(fsys afero.Fs, path string, info fs.FileInfo, withHash bool) (*Fingerprint, error) {
+	fp := &Fingerprint{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Inode:   fileID(info),
+	}
+	if withHash && info.Mode().IsRegular() {
+		hash, err := hashFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		fp.Hash = hash
+	}
+	return fp, nil
+}
+
+// verifyFingerprint checks that the target of c on fsys still matches the
+// fingerprint taken at plan time. Changes without one always pass.
+func verifyFingerprint(fsys afero.Fs, c Change) error {
+	fp := c.Fingerprint
+	if fp == nil {
+		return nil
+	}
+
+	info, err := lstat(fsys, c.Target)
+	if os.IsNotExist(err) {
+		return &StaleError{Change: c, Detail: "file no longer exists"}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.IsDir():
+		return &StaleError{Change: c, Detail: "now a directory"}
+	case fp.Inode != 0 && fileID(info) != 0 && fileID(info) != fp.Inode:
+		return &StaleError{Change: c, Detail: "replaced by another file"}
+	case info.Size() != fp.Size:
+		return &StaleError{Change: c, Detail: fmt.Sprintf("size %d, planned %d", info.Size(), fp.Size)}
+	case !info.ModTime().Equal(fp.ModTime):
+		return &StaleError{Change: c, Detail: fmt.Sprintf("modified %s, planned %s",
+			info.ModTime().Format(time.RFC3339), fp.ModTime.Format(time.RFC3339))}
+	}
+
+	if fp.Hash != "" {
+		hash, err := hashFile(fsys, c.Target)
+		if err != nil {
+			return err
+		}
+		if hash != fp.Hash {
+			return &StaleError{Change: c, Detail: "content changed"}
+		}
+	}
+	return nil
+}
+
+// hashFile returns the sha256 of the file content as "sha256:<hex>"
+func hashFile(fsys afero.Fs, path string) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lstat stats path without following symlinks when fsys supports it
+func lstat(fsys afero.Fs, path string) (fs.FileInfo, error) {
+	if l, ok := fsys.(afero.Lstater); ok {
+		info, _, err := l.LstatIfPossible(path)
+		return info, err
+	}
+	return fsys.Stat(path)
+}
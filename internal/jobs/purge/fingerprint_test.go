@@ -0,0 +1,158 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyFingerprint(t *testing.T) {
+	planned := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		withHash  bool
+		drift     func(fs afero.Fs, path string) error
+		wantStale bool
+	}{
+		{
+			name:  "unchanged",
+			drift: func(afero.Fs, string) error { return nil },
+		},
+		{
+			name: "size changed",
+			drift: func(fs afero.Fs, path string) error {
+				return afero.WriteFile(fs, path, []byte("longer content"), 0644)
+			},
+			wantStale: true,
+		},
+		{
+			name: "mtime changed",
+			drift: func(fs afero.Fs, path string) error {
+				later := planned.Add(time.Hour)
+				return fs.Chtimes(path, later, later)
+			},
+			wantStale: true,
+		},
+		{
+			name:     "content changed with same size and mtime",
+			withHash: true,
+			drift: func(fs afero.Fs, path string) error {
+				if err := afero.WriteFile(fs, path, []byte("JUNK"), 0644); err != nil {
+					return err
+				}
+				return fs.Chtimes(path, planned, planned)
+			},
+			wantStale: true,
+		},
+		{
+			name: "file removed",
+			drift: func(fs afero.Fs, path string) error {
+				return fs.Remove(path)
+			},
+			wantStale: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := useMemFs(t)
+			path := filepath.Join(string(filepath.Separator), "scan", "a.tmp")
+			require.NoError(t, afero.WriteFile(fs, path, []byte("junk"), 0644))
+			require.NoError(t, fs.Chtimes(path, planned, planned))
+
+			info, err := fs.Stat(path)
+			require.NoError(t, err)
+			fp, err := newFingerprint(fs, path, info, tt.withHash)
+			require.NoError(t, err)
+			if tt.withHash {
+				assert.Contains(t, fp.Hash, "sha256:")
+			}
+
+			require.NoError(t, tt.drift(fs, path))
+
+			err = verifyFingerprint(fs, Change{Type: DeleteFile, Target: path, Fingerprint: fp})
+			var stale *StaleError
+			assert.Equal(t, tt.wantStale, errors.As(err, &stale), "verifyFingerprint() = %v", err)
+		})
+	}
+}
+
+func TestApplyAllWithOptionsSkipsStale(t *testing.T) {
+	fs := useMemFs(t)
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	root := filepath.Join(string(filepath.Separator), "scan")
+	fresh := filepath.Join(root, "fresh.tmp")
+	drifted := filepath.Join(root, "drifted.tmp")
+	var changes []Change
+	for _, path := range []string{fresh, drifted} {
+		require.NoError(t, afero.WriteFile(fs, path, []byte("junk"), 0644))
+		info, err := fs.Stat(path)
+		require.NoError(t, err)
+		fp, err := newFingerprint(fs, path, info, false)
+		require.NoError(t, err)
+		changes = append(changes, Change{Type: DeleteFile, Target: path, Fingerprint: fp})
+	}
+	require.NoError(t, afero.WriteFile(fs, drifted, []byte("now important"), 0644))
+
+	t.Run("strict aborts the run", func(t *testing.T) {
+		results, err := ApplyAllWithOptions(changes, ApplyOptions{Strict: true})
+		applied := results.Applied()
+		var stale *StaleError
+		require.ErrorAs(t, err, &stale)
+		assert.Equal(t, drifted, stale.Change.Target)
+		assert.Empty(t, applied)
+
+		exists, _ := afero.Exists(fs, fresh)
+		assert.True(t, exists, "nothing should be applied in strict mode")
+	})
+
+	t.Run("default skips drifted targets", func(t *testing.T) {
+		results, err := ApplyAllWithOptions(changes, ApplyOptions{})
+		applied := results.Applied()
+		var stale *StaleError
+		require.ErrorAs(t, err, &stale)
+		require.Len(t, applied, 1)
+		assert.Equal(t, fresh, applied[0].Target)
+
+		exists, _ := afero.Exists(fs, drifted)
+		assert.True(t, exists, "drifted file must be kept")
+	})
+}
+
+func TestPreviewChangesFingerprints(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.tmp")
+	if err := os.WriteFile(file, []byte("junk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := previewChanges(context.Background(), afero.NewOsFs(), dir, &Config{ExtensionsToDelete: []string{".tmp"}, FingerprintHash: true}, 0, nil)
+	if err != nil {
+		t.Fatalf("PreviewChanges failed: %v", err)
+	}
+
+	for _, c := range changes {
+		if c.Type != DeleteFile {
+			continue
+		}
+		if c.Fingerprint == nil || c.Fingerprint.Size != 4 || c.Fingerprint.Hash == "" {
+			t.Fatalf("Fingerprint = %+v, want size 4 with hash", c.Fingerprint)
+		}
+		if err := verifyFingerprint(afero.NewOsFs(), c); err != nil {
+			t.Errorf("verifyFingerprint() on unchanged file = %v", err)
+		}
+		return
+	}
+	t.Fatalf("no delete planned in %+v", changes)
+}
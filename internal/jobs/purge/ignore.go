@@ -0,0 +1,195 @@
+package purge
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/afero"
+
+	"housekeeper/internal/common"
+)
+
+// IgnoreFileName is the per-directory exclusion file, read with gitignore semantics
+const IgnoreFileName = ".housekeeperignore"
+
+// ignorePattern is one gitignore-style line
+type ignorePattern struct {
+	base     string // directory of the defining file relative to the root, "" for the root
+	glob     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// parseIgnoreLine parses a single line. ok is false for blank lines and comments.
+func parseIgnoreLine(base, line string) (p ignorePattern, ok bool, err error) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false, nil
+	}
+
+	p.base = base
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// A slash anywhere but the end ties the pattern to the defining directory
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	if line == "" {
+		return ignorePattern{}, false, nil
+	}
+	if !doublestar.ValidatePattern(line) {
+		return ignorePattern{}, false, fmt.Errorf("invalid pattern %q", line)
+	}
+
+	p.glob = line
+	return p, true, nil
+}
+
+// matches reports whether rel, slash-separated and relative to the root, is
+// selected by the pattern
+func (p ignorePattern) matches(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.base != "" {
+		if !strings.HasPrefix(rel, p.base+"/") {
+			return false
+		}
+		rel = rel[len(p.base)+1:]
+	}
+
+	if !p.anchored {
+		ok, _ := doublestar.Match(p.glob, path.Base(rel))
+		return ok
+	}
+	ok, _ := doublestar.Match(p.glob, rel)
+	return ok
+}
+
+// excluder tracks the exclusion patterns in effect for each directory of a
+// walk. It is safe for concurrent use.
+type excluder struct {
+	fsys     afero.Fs
+	root     string
+	mu       sync.RWMutex
+	patterns map[string][]ignorePattern // keyed by slash-separated dir relative to root
+}
+
+// newExcluder creates an excluder for a walk of root on fsys. The given
+// patterns apply from the root down and are overridden by any ignore file.
+func newExcluder(fsys afero.Fs, root string, excludes []string) (*excluder, error) {
+	var base []ignorePattern
+	for _, line := range excludes {
+		p, ok, err := parseIgnoreLine("", line)
+		if err != nil {
+			return nil, fmt.Errorf("exclude: %w", err)
+		}
+		if ok {
+			base = append(base, p)
+		}
+	}
+
+	return &excluder{
+		fsys:     fsys,
+		root:     root,
+		patterns: map[string][]ignorePattern{".": base},
+	}, nil
+}
+
+func (e *excluder) rel(p string) string {
+	rel, err := filepath.Rel(e.root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// enterDir loads the ignore file of dir, if any. It must be called for a
+// directory before anything inside it is checked.
+func (e *excluder) enterDir(dir string) {
+	rel := e.rel(dir)
+	e.mu.RLock()
+	inherited := e.patterns[rel]
+	if rel != "." {
+		inherited = e.patterns[path.Dir(rel)]
+	}
+	e.mu.RUnlock()
+
+	base := rel
+	if base == "." {
+		base = ""
+	}
+	own := readIgnoreFile(e.fsys, filepath.Join(dir, IgnoreFileName), base)
+
+	e.mu.Lock()
+	e.patterns[rel] = append(slices.Clip(inherited), own...)
+	e.mu.Unlock()
+}
+
+// excluded reports whether p should be skipped. The last matching pattern wins.
+func (e *excluder) excluded(p string, isDir bool) bool {
+	rel := e.rel(p)
+	if rel == "." {
+		return false
+	}
+
+	e.mu.RLock()
+	patterns := e.patterns[path.Dir(rel)]
+	e.mu.RUnlock()
+
+	excluded := false
+	for _, pattern := range patterns {
+		if pattern.matches(rel, isDir) {
+			excluded = !pattern.negate
+		}
+	}
+	return excluded
+}
+
+// readIgnoreFile returns the patterns of an ignore file. Unreadable files and
+// invalid lines are logged and skipped so a bad file never aborts a scan.
+func readIgnoreFile(fsys afero.Fs, file, base string) []ignorePattern {
+	data, err := afero.ReadFile(fsys, file)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			common.Warn.Printf("Reading %s: %v", file, err)
+		}
+		return nil
+	}
+
+	var patterns []ignorePattern
+	for i, line := range strings.Split(string(data), "\n") {
+		p, ok, err := parseIgnoreLine(base, line)
+		if err != nil {
+			common.Warn.Printf("%s:%d: %v", file, i+1, err)
+			continue
+		}
+		if ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
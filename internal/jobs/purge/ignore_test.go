@@ -0,0 +1,111 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIgnorePatternMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		line  string
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{name: "name matches at any depth", line: "node_modules", rel: "web/app/node_modules", isDir: true, want: true},
+		{name: "glob on file name", line: "*.iso", rel: "backups/disk.iso", want: true},
+		{name: "directory-only skips files", line: "build/", rel: "build", want: false},
+		{name: "directory-only matches dirs", line: "build/", rel: "src/build", isDir: true, want: true},
+		{name: "leading slash anchors to root", line: "/cache", rel: "cache", isDir: true, want: true},
+		{name: "anchored does not match deeper", line: "/cache", rel: "app/cache", isDir: true, want: false},
+		{name: "middle slash anchors", line: "docs/*.tmp", rel: "docs/a.tmp", want: true},
+		{name: "middle slash does not match deeper", line: "docs/*.tmp", rel: "src/docs/a.tmp", want: false},
+		{name: "double star spans directories", line: "**/venv/**", rel: "a/b/venv/lib", isDir: true, want: true},
+		{name: "pattern relative to its file", base: "photos", line: "/raw", rel: "photos/raw", isDir: true, want: true},
+		{name: "pattern outside its file", base: "photos", line: "raw", rel: "raw", isDir: true, want: false},
+		{name: "escaped hash is literal", line: `\#notes`, rel: "#notes", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok, err := parseIgnoreLine(tt.base, tt.line)
+			if err != nil || !ok {
+				t.Fatalf("parseIgnoreLine(%q) = ok %v, err %v", tt.line, ok, err)
+			}
+			if got := p.matches(tt.rel, tt.isDir); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIgnoreLineSkipsBlankAndComments(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "\r"} {
+		if _, ok, err := parseIgnoreLine("", line); ok || err != nil {
+			t.Errorf("parseIgnoreLine(%q) = ok %v, err %v, want skipped", line, ok, err)
+		}
+	}
+}
+
+func TestPreviewChangesHonorsExclusions(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		filepath.Join(".git", "index.tmp"):                     "x",
+		filepath.Join("src", "keep.txt"):                       "x",
+		filepath.Join("src", "junk.tmp"):                       "x",
+		filepath.Join("src", IgnoreFileName):                   "*.tmp\n!important.tmp\n",
+		filepath.Join("src", "important.tmp"):                  "x",
+		filepath.Join("src", "nested", "deep.tmp"):             "x",
+		filepath.Join("backup", IgnoreFileName):                "*\n",
+		filepath.Join("backup", "old.tmp"):                     "x",
+		filepath.Join("downloads", "movie.tmp"):                "x",
+		filepath.Join("downloads", "vault", "secret.tmp"):      "x",
+		filepath.Join("downloads", "vault", "ignored", "x.md"): "x",
+	}
+	for name, content := range files {
+		full := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "node_modules", "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		ExtensionsToDelete: []string{".tmp"},
+		Excludes:           []string{".git/", "node_modules/", "/downloads/vault"},
+	}
+	changes, err := PreviewChanges(root, cfg)
+	if err != nil {
+		t.Fatalf("PreviewChanges failed: %v", err)
+	}
+
+	var got []Change
+	for _, c := range changes {
+		rel, _ := filepath.Rel(root, c.Target)
+		got = append(got, Change{Type: c.Type, Target: rel})
+	}
+	sort.Slice(got, func(i, j int) bool {
+		if got[i].Type != got[j].Type {
+			return got[i].Type < got[j].Type
+		}
+		return got[i].Target < got[j].Target
+	})
+
+	want := []Change{
+		{Type: DeleteFile, Target: filepath.Join("downloads", "movie.tmp")},
+		{Type: DeleteFile, Target: filepath.Join("src", "important.tmp")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviewChanges() = %v, want %v", got, want)
+	}
+}
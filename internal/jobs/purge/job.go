@@ -1,21 +1,62 @@
 package purge
 
+import (
+    "context"
+    "iter"
+
+    "github.com/spf13/afero"
+)
 
 // Job represents a directory cleanup task
 type Job struct {
     Dir string
     Cfg *Config
+    Fs  afero.Fs // filesystem to plan and apply on
+
+    Workers int // directories read at once when planning; DefaultWalkWorkers if 0
+
+    Progress ProgressObserver // optional, told about planning and applying as it happens
 }
 
-// NewJob creates a new purge job
+// NewJob creates a new purge job on AppFs
 func NewJob(dir string, cfg *Config) *Job {
     return &Job{
         Dir: dir,
         Cfg: cfg,
+        Fs:  AppFs,
+    }
+}
+
+// Plan runs a dry run and returns all changes that would be made. It stops
+// with ctx's error once ctx is done.
+func (j *Job) Plan(ctx context.Context) ([]Change, error) {
+    return previewChanges(ctx, j.fs(), j.Dir, j.Cfg, j.Workers, j.Progress)
+}
+
+// PlanSeq streams the changes Plan would return, see PreviewChangesSeq
+func (j *Job) PlanSeq(ctx context.Context) iter.Seq2[Change, error] {
+    return previewChangesSeq(ctx, j.fs(), j.Dir, j.Cfg, j.Workers, j.Progress)
+}
+
+// Apply applies changes on the job's filesystem. Once ctx is done it
+// stops between changes, see ApplyAllContext. Progress goes to the job's
+// observer unless opts has its own.
+func (j *Job) Apply(ctx context.Context, changes []Change, opts ApplyOptions) (ApplyResults, error) {
+    opts.Fs = j.fs()
+    if opts.Progress == nil {
+        opts.Progress = j.Progress
     }
+    return ApplyAllContext(ctx, changes, opts)
 }
 
-// Plan runs a dry run and returns all changes that would be made
-func (j *Job) Plan() ([]Change, error) {
-    return PreviewChanges(j.Dir, j.Cfg)
-}
\ No newline at end of file
+// Undo reverts the changes recorded in a journal on the job's filesystem
+func (j *Job) Undo(journalPath string) ([]Change, error) {
+    return undo(j.fs(), journalPath)
+}
+
+func (j *Job) fs() afero.Fs {
+    if j.Fs == nil {
+        return AppFs
+    }
+    return j.Fs
+}
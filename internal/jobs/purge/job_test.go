@@ -1,9 +1,12 @@
 package purge
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/spf13/afero"
 )
 
 func TestJob_Plan(t *testing.T) {
@@ -94,7 +97,7 @@ func TestJob_Plan(t *testing.T) {
 
 			// Create job and run plan
 			job := NewJob(testDir, tt.cfg)
-			changes, err := job.Plan()
+			changes, err := job.Plan(context.Background())
 
 			// Check error conditions
 			if (err != nil) != tt.wantErr {
@@ -134,3 +137,89 @@ func TestJob_Plan(t *testing.T) {
 		})
 	}
 }
+
+func TestJobOnMemMapFs(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	files := map[string]string{
+		"a.tmp":               "junk",
+		"b.JPG":               "img",
+		"keep.txt":            "keep",
+		"empty/nested/x.tmp":  "junk",
+		"ignored/y.tmp":       "junk",
+		IgnoreFileName:        "ignored/\n",
+		"locked/readonly.tmp": "junk",
+	}
+	for name, data := range files {
+		if err := afero.WriteFile(fsys, filepath.Join(root, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fsys.Chmod(filepath.Join(root, "locked", "readonly.tmp"), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	job := NewJob(root, &Config{ExtensionsToDelete: []string{".tmp"}})
+	job.Fs = fsys
+
+	changes, err := job.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	results, err := job.Apply(context.Background(), changes, ApplyOptions{JournalPath: filepath.Join(string(filepath.Separator), "journal.jsonl")})
+	applied := results.Applied()
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(applied) != len(changes) {
+		t.Errorf("applied %d of %d changes", len(applied), len(changes))
+	}
+
+	for name, want := range map[string]bool{
+		"a.tmp":               false,
+		"b.JPG":               false,
+		"b.jpg":               true,
+		"keep.txt":            true,
+		"empty":               false,
+		"ignored/y.tmp":       true,
+		"locked/readonly.tmp": false,
+	} {
+		if got, _ := afero.Exists(fsys, filepath.Join(root, name)); got != want {
+			t.Errorf("exists(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	if _, err := job.Undo(filepath.Join(string(filepath.Separator), "journal.jsonl")); err == nil {
+		t.Error("Undo() of hard deletes should report an error")
+	}
+	if got, _ := afero.Exists(fsys, filepath.Join(root, "b.JPG")); !got {
+		t.Error("Undo() should restore the rename")
+	}
+}
+
+func TestJobOnCopyOnWriteOverlay(t *testing.T) {
+	base := t.TempDir()
+	file := filepath.Join(base, "a.tmp")
+	if err := os.WriteFile(file, []byte("junk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlay := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(afero.NewOsFs()), afero.NewMemMapFs())
+	job := NewJob(base, &Config{ExtensionsToDelete: []string{".tmp"}})
+	job.Fs = overlay
+
+	changes, err := job.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Plan() = %+v, want the file and the emptied root", changes)
+	}
+	if _, err := job.Apply(context.Background(), changes, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("file on disk should be untouched: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package purge
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"housekeeper/internal/common"
+)
+
+// JournalEntry records an applied change together with the state needed to
+// revert it
+type JournalEntry struct {
+	Change     Change      `json:"change"`
+	AppliedAt  time.Time   `json:"applied_at"`
+	Mode       fs.FileMode `json:"mode"` // of the target before the change
+	Size       int64       `json:"size"`
+	DeleteMode DeleteMode  `json:"delete_mode,omitempty"`
+	MovedTo    string      `json:"moved_to,omitempty"` // quarantine or trash location of a deleted file
+}
+
+// DefaultJournalPath creates a new, empty journal file on AppFs under the
+// user data dir, named after the given time, and returns its path
+func DefaultJournalPath(now time.Time) (string, error) {
+	dir, err := dataHome()
+	if err != nil {
+		return "", err
+	}
+	return createJournal(AppFs, filepath.Join(dir, "housekeeper", "journals"), now)
+}
+
+// createJournal creates an empty journal in dir named after now. A journal
+// of another apply in the same second is never reused: the name then gets
+// a numeric suffix, e.g. 20240831-223208-2.jsonl.
+func createJournal(fsys afero.Fs, dir string, now time.Time) (string, error) {
+	if err := fsys.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating journal dir: %w", err)
+	}
+	name := now.Format("20060102-150405")
+	for i := 1; ; i++ {
+		path := filepath.Join(dir, name+".jsonl")
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.jsonl", name, i))
+		}
+		f, err := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return path, f.Close()
+	}
+}
+
+// newJournalEntry captures the state of the change target before applying
+// it. Paths are made absolute, so the journal can be undone from any dir.
+func newJournalEntry(fsys afero.Fs, change Change, mode DeleteMode) JournalEntry {
+	change.Target = absOrSelf(change.Target)
+	if change.NewName != "" {
+		change.NewName = absOrSelf(change.NewName)
+	}
+	entry := JournalEntry{Change: change}
+	if change.Type == DeleteFile {
+		entry.DeleteMode = mode
+	}
+	if info, err := fsys.Stat(change.Target); err == nil {
+		entry.Mode = info.Mode()
+		entry.Size = info.Size()
+	}
+	return entry
+}
+
+// absOrSelf returns path made absolute, or path as is if that fails
+func absOrSelf(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// journalWriter appends entries to a journal file, one JSON object per line
+type journalWriter struct {
+	fsys afero.Fs
+	path string
+	mu   sync.Mutex
+}
+
+func openJournal(fsys afero.Fs, path string) (*journalWriter, error) {
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating journal dir: %w", err)
+	}
+	return &journalWriter{fsys: fsys, path: path}, nil
+}
+
+func (j *journalWriter) append(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry.AppliedAt = timeNow()
+	return appendJSONLine(j.fsys, j.path, entry)
+}
+
+// ReadJournal returns the entries of a journal in the order they were applied
+func ReadJournal(path string) ([]JournalEntry, error) {
+	return readJournal(AppFs, path)
+}
+
+func readJournal(fsys afero.Fs, path string) ([]JournalEntry, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Undo reverts the changes recorded in a journal, newest first. Renames are
+// reverted, quarantined and trashed files are moved back and removed
+// directories are recreated. Files deleted for good cannot be restored and
+// are reported as errors. It returns the reverted changes and the first error.
+func Undo(journalPath string) ([]Change, error) {
+	return undo(AppFs, journalPath)
+}
+
+func undo(fsys afero.Fs, journalPath string) ([]Change, error) {
+	entries, err := readJournal(fsys, journalPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+
+	var reverted []Change
+	var undoErr error
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := revertEntry(fsys, entries[i]); err != nil {
+			common.Error.Printf("Failed to undo %s: %v", entries[i].Change.Target, err)
+			if undoErr == nil {
+				undoErr = err
+			}
+			continue
+		}
+		reverted = append(reverted, entries[i].Change)
+	}
+
+	return reverted, undoErr
+}
+
+func revertEntry(fsys afero.Fs, e JournalEntry) error {
+	c := e.Change
+
+	switch c.Type {
+	case RenameFile:
+		common.Info.Printf("Restoring name %s → %s\n", c.NewName, c.Target)
+		if err := ensureAbsent(fsys, c.Target); err != nil {
+			return err
+		}
+		if err := fsys.Rename(c.NewName, c.Target); err != nil {
+			return fmt.Errorf("renaming %s → %s: %w", c.NewName, c.Target, err)
+		}
+		restoreMode(fsys, c.Target, e.Mode) // applying unlocked the file
+	case DeleteFile:
+		if e.MovedTo == "" {
+			return fmt.Errorf("%s was deleted permanently and cannot be restored", c.Target)
+		}
+		common.Info.Printf("Restoring %s from %s\n", c.Target, e.MovedTo)
+		if err := ensureAbsent(fsys, c.Target); err != nil {
+			return err
+		}
+		if err := fsys.MkdirAll(filepath.Dir(c.Target), 0755); err != nil {
+			return err
+		}
+		if err := moveFile(fsys, e.MovedTo, c.Target); err != nil {
+			return fmt.Errorf("restoring %s: %w", c.Target, err)
+		}
+		restoreMode(fsys, c.Target, e.Mode)
+		if e.DeleteMode == DeleteTrash {
+			removeTrashInfo(fsys, e.MovedTo)
+		}
+	case RemoveDir:
+		common.Info.Printf("Recreating directory %s\n", c.Target)
+		perm := e.Mode.Perm()
+		if perm == 0 {
+			perm = 0755
+		}
+		if err := fsys.MkdirAll(c.Target, perm); err != nil {
+			return fmt.Errorf("recreating dir %s: %w", c.Target, err)
+		}
+	default:
+		return fmt.Errorf("unknown change type: %v", c.Type)
+	}
+	return nil
+}
+
+// restoreMode sets the permissions of path back to those of mode, if the
+// journal recorded any
+func restoreMode(fsys afero.Fs, path string, mode fs.FileMode) {
+	if mode == 0 {
+		return
+	}
+	if err := fsys.Chmod(path, mode.Perm()); err != nil {
+		common.Warn.Printf("Restoring mode of %s: %v", path, err)
+	}
+}
+
+func ensureAbsent(fsys afero.Fs, path string) error {
+	exists, err := afero.Exists(fsys, path)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%s already exists", path)
+	}
+	return nil
+}
+
+// removeTrashInfo deletes the .trashinfo file belonging to a file restored
+// from the trash's files/ dir
+func removeTrashInfo(fsys afero.Fs, trashed string) {
+	trashDir := filepath.Dir(filepath.Dir(trashed))
+	info := filepath.Join(trashDir, "info", filepath.Base(trashed)+".trashinfo")
+	if err := fsys.Remove(info); err != nil {
+		common.Warn.Printf("Removing %s: %v", info, err)
+	}
+}
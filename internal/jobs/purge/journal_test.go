@@ -0,0 +1,170 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoRevertsJournal(t *testing.T) {
+	fs := useMemFs(t)
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	root := filepath.Join(string(filepath.Separator), "scan")
+	renamed := filepath.Join(root, "photo.JPG")
+	deleted := filepath.Join(root, "old.tmp")
+	emptyDir := filepath.Join(root, "empty")
+	journal := filepath.Join(string(filepath.Separator), "journals", "run.jsonl")
+
+	require.NoError(t, afero.WriteFile(fs, renamed, []byte("img"), 0644))
+	require.NoError(t, afero.WriteFile(fs, deleted, []byte("junk"), 0600))
+	require.NoError(t, fs.MkdirAll(emptyDir, 0750))
+
+	changes := []Change{
+		{Type: RenameFile, Target: renamed, NewName: filepath.Join(root, "photo.jpg")},
+		{Type: DeleteFile, Target: deleted},
+		{Type: RemoveDir, Target: emptyDir},
+	}
+	results, err := ApplyAllWithOptions(changes, ApplyOptions{
+		DeleteMode:    DeleteQuarantine,
+		QuarantineDir: filepath.Join(string(filepath.Separator), "q"),
+		JournalPath:   journal,
+	})
+	applied := results.Applied()
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+
+	entries, err := ReadJournal(journal)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.NotEmpty(t, entries[1].MovedTo, "quarantine location should be journaled")
+	assert.Equal(t, DeleteQuarantine, entries[1].DeleteMode)
+
+	reverted, err := Undo(journal)
+	require.NoError(t, err)
+	require.Len(t, reverted, 3)
+	assert.Equal(t, RemoveDir, reverted[0].Type, "undo runs newest first")
+
+	data, err := afero.ReadFile(fs, renamed)
+	require.NoError(t, err)
+	assert.Equal(t, "img", string(data))
+
+	info, err := fs.Stat(deleted)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), info.Size())
+
+	info, err = fs.Stat(emptyDir)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestUndoHardDeleteFails(t *testing.T) {
+	fs := useMemFs(t)
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	file := filepath.Join(string(filepath.Separator), "scan", "old.tmp")
+	journal := filepath.Join(string(filepath.Separator), "run.jsonl")
+	require.NoError(t, afero.WriteFile(fs, file, []byte("x"), 0644))
+
+	_, err := ApplyAllWithOptions([]Change{{Type: DeleteFile, Target: file}}, ApplyOptions{JournalPath: journal})
+	require.NoError(t, err)
+
+	reverted, err := Undo(journal)
+	assert.Error(t, err)
+	assert.Empty(t, reverted)
+}
+
+func TestUndoFromAnotherDir(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewOsFs()
+	t.Cleanup(func() { AppFs = originalFs })
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "e", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "e", "B.JPEG"), []byte("img"), 0644))
+	journal := filepath.Join(t.TempDir(), "run.jsonl")
+
+	// Planned relative to the scan's working dir, as for -dir e
+	require.NoError(t, os.Chdir(root))
+	changes := []Change{
+		{Type: RenameFile, Target: filepath.Join("e", "B.JPEG"), NewName: filepath.Join("e", "B.jpg")},
+		{Type: RemoveDir, Target: filepath.Join("e", "sub")},
+	}
+	_, err = ApplyAllWithOptions(changes, ApplyOptions{JournalPath: journal})
+	require.NoError(t, err)
+
+	entries, err := ReadJournal(journal)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, filepath.Join(root, "e", "B.JPEG"), entries[0].Change.Target)
+	assert.Equal(t, filepath.Join(root, "e", "B.jpg"), entries[0].Change.NewName)
+
+	require.NoError(t, os.Chdir(t.TempDir()))
+	_, err = Undo(journal)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(root, "e", "B.JPEG"))
+	require.NoError(t, err)
+	assert.Equal(t, "img", string(data))
+	assert.DirExists(t, filepath.Join(root, "e", "sub"))
+}
+
+func TestUndoRenameRestoresMode(t *testing.T) {
+	fs := useMemFs(t)
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(fsys afero.Fs, path string) error { return fsys.Chmod(path, 0666) }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	src := filepath.Join(string(filepath.Separator), "scan", "photo.JPEG")
+	dst := filepath.Join(string(filepath.Separator), "scan", "photo.jpg")
+	journal := filepath.Join(string(filepath.Separator), "journals", "run.jsonl")
+	require.NoError(t, afero.WriteFile(fs, src, []byte("img"), 0600))
+
+	_, err := ApplyAllWithOptions([]Change{{Type: RenameFile, Target: src, NewName: dst}}, ApplyOptions{JournalPath: journal})
+	require.NoError(t, err)
+	info, err := fs.Stat(dst)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0666), info.Mode().Perm(), "applying unlocks the file")
+
+	_, err = Undo(journal)
+	require.NoError(t, err)
+	info, err = fs.Stat(src)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestDefaultJournalPathIsNew(t *testing.T) {
+	fs := useMemFs(t)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(string(filepath.Separator), "data"))
+	now := time.Date(2024, 8, 31, 22, 32, 8, 0, time.Local)
+
+	first, err := DefaultJournalPath(now)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(string(filepath.Separator), "data", "housekeeper", "journals", "20240831-223208.jsonl"), first)
+	exists, _ := afero.Exists(fs, first)
+	assert.True(t, exists, "the journal is created right away")
+
+	// Another apply in the same second gets its own journal
+	second, err := DefaultJournalPath(now)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Dir(first), "20240831-223208-2.jsonl"), second)
+	third, err := DefaultJournalPath(now)
+	require.NoError(t, err)
+	assert.NotContains(t, []string{first, second}, third)
+}
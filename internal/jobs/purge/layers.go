@@ -0,0 +1,335 @@
+package purge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/spf13/afero"
+
+	"housekeeper/internal/common"
+)
+
+// StringList is a list setting of a config file. A plain list
+// replaces the inherited list, an object edits it:
+//
+//	{"add": [".o"], "remove": [".log"]}
+//	{"replace": [".o", ".a"]}
+//
+// Replace applies first, then Add, then Remove.
+type StringList struct {
+	Replace *[]string `json:"replace,omitempty"`
+	Add     []string  `json:"add,omitempty"`
+	Remove  []string  `json:"remove,omitempty"`
+}
+
+// StringMap is a map setting of a config file. A plain table
+// replaces the inherited map, a table with only add, remove and replace
+// keys edits it:
+//
+//	{"add": {".jpeg": ".jpg"}, "remove": [".htm"]}
+//
+// Replace applies first, then Add, then Remove, which takes keys.
+type StringMap struct {
+	Replace *map[string]string `json:"replace,omitempty"`
+	Add     map[string]string  `json:"add,omitempty"`
+	Remove  []string           `json:"remove,omitempty"`
+}
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var list []string
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		*l = StringList{Replace: &list}
+		return nil
+	}
+	type edit StringList // without the methods
+	return decodeStrict(data, (*edit)(l))
+}
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if l.Replace != nil && len(l.Add) == 0 && len(l.Remove) == 0 {
+		return json.Marshal(*l.Replace)
+	}
+	type edit StringList
+	return json.Marshal(edit(l))
+}
+
+func (m *StringMap) UnmarshalJSON(data []byte) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	for key := range keys {
+		if key == "add" || key == "remove" || key == "replace" {
+			type edit StringMap
+			return decodeStrict(data, (*edit)(m))
+		}
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	*m = StringMap{Replace: &entries}
+	return nil
+}
+
+func (m StringMap) MarshalJSON() ([]byte, error) {
+	if m.Replace != nil && len(m.Add) == 0 && len(m.Remove) == 0 {
+		return json.Marshal(*m.Replace)
+	}
+	type edit StringMap
+	return json.Marshal(edit(m))
+}
+
+// decodeStrict decodes data into v, rejecting unknown fields
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// apply returns list edited by l, leaving list as is. A nil l keeps it.
+func (l *StringList) apply(list []string) []string {
+	if l == nil {
+		return list
+	}
+	var edited []string
+	if l.Replace != nil {
+		edited = appendUnique(edited, *l.Replace...)
+	} else {
+		edited = appendUnique(edited, list...)
+	}
+	edited = appendUnique(edited, l.Add...)
+	return slices.DeleteFunc(edited, func(item string) bool {
+		return slices.Contains(l.Remove, item)
+	})
+}
+
+// given returns the items l sets itself
+func (l *StringList) given() []string {
+	if l == nil {
+		return nil
+	}
+	var items []string
+	if l.Replace != nil {
+		items = append(items, *l.Replace...)
+	}
+	return append(items, l.Add...)
+}
+
+// apply returns m edited by e, leaving m as is. A nil e keeps it.
+func (e *StringMap) apply(m map[string]string) map[string]string {
+	if e == nil {
+		return m
+	}
+	edited := maps.Clone(m)
+	if e.Replace != nil {
+		edited = maps.Clone(*e.Replace)
+	}
+	if edited == nil && len(e.Add) > 0 {
+		edited = make(map[string]string, len(e.Add))
+	}
+	maps.Copy(edited, e.Add)
+	for _, key := range e.Remove {
+		delete(edited, key)
+	}
+	return edited
+}
+
+// given returns the entries e sets itself
+func (e *StringMap) given() map[string]string {
+	if e == nil {
+		return nil
+	}
+	entries := make(map[string]string)
+	if e.Replace != nil {
+		maps.Copy(entries, *e.Replace)
+	}
+	maps.Copy(entries, e.Add)
+	return entries
+}
+
+func replaceList(list []string) *StringList {
+	if len(list) == 0 {
+		return nil
+	}
+	return &StringList{Replace: &list}
+}
+
+func replaceMap(m map[string]string) *StringMap {
+	if len(m) == 0 {
+		return nil
+	}
+	return &StringMap{Replace: &m}
+}
+
+// checkLayer rejects the settings a config file in a subdirectory cannot
+// change, as they apply to the whole scan
+func (f *ConfigFile) checkLayer() error {
+	var setting string
+	switch {
+	case len(f.Excludes) > 0:
+		setting = "excludes (use a " + IgnoreFileName + " file instead)"
+	case f.Rename.Collisions != "":
+		setting = "rename.collisions"
+	case f.Safety != SafetySettings{}:
+		setting = "safety"
+	case f.Logging != common.LoggingSettings{}:
+		setting = "logging"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%s only applies to the whole scan, not to a subdirectory", setting)
+}
+
+// overlay returns base with the settings of a config file in a
+// subdirectory layered over it. Extensions and replacements are edited,
+// prefixes replaced, rules added, and hidden files deleted if it says so.
+func (f *ConfigFile) overlay(base *Config, path string) (*Config, error) {
+	if err := f.checkLayer(); err != nil {
+		return nil, err
+	}
+
+	// The file's own settings, to record it as their source
+	own := &Config{
+		ExtensionsToDelete:    f.Delete.Extensions.given(),
+		ExtensionReplacements: f.Rename.ExtensionReplacements.given(),
+		PrefixesToDelete:      f.Delete.Prefixes,
+		DeleteHiddenFiles:     f.Delete.HiddenFiles,
+		DeleteRules:           slices.Clone(f.Delete.Rules),
+	}
+	stampSources(own, path)
+
+	cfg := *base
+	cfg.ExtensionsToDelete = f.Delete.Extensions.apply(base.ExtensionsToDelete)
+	cfg.ExtensionReplacements = f.Rename.ExtensionReplacements.apply(base.ExtensionReplacements)
+	if len(f.Delete.Prefixes) > 0 {
+		cfg.PrefixesToDelete = f.Delete.Prefixes
+	}
+	cfg.DeleteHiddenFiles = base.DeleteHiddenFiles || f.Delete.HiddenFiles
+	cfg.DeleteRules = append(slices.Clip(base.DeleteRules), own.DeleteRules...)
+	cfg.Sources = maps.Clone(base.Sources)
+	if cfg.Sources == nil {
+		cfg.Sources = make(map[string]string)
+	}
+	maps.Copy(cfg.Sources, own.Sources)
+	return &cfg, nil
+}
+
+// configLayers tracks the config in effect for each directory of a walk:
+// the config of the scan, with the DirConfigName files of subdirectories
+// layered over it for their subtree. It is safe for concurrent use.
+type configLayers struct {
+	fsys   afero.Fs
+	mu     sync.Mutex
+	layers map[string]*configLayer // keyed by cleaned dir
+	err    error                   // the first config file that could not be used
+}
+
+// configLayer is the config in effect for a subtree
+type configLayer struct {
+	cfg   *Config
+	rules []deleteRule
+}
+
+func newConfigLayers(fsys afero.Fs, root string, cfg *Config) (*configLayers, error) {
+	rules, err := compileDeleteRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &configLayers{
+		fsys:   fsys,
+		layers: map[string]*configLayer{filepath.Clean(root): {cfg: cfg, rules: rules}},
+	}, nil
+}
+
+// forDir returns the layer in effect for dir, which must be under the
+// root, loading the config files of dir and its parents as needed. Once a
+// config file fails, every call returns its error.
+func (l *configLayers) forDir(dir string) (*configLayer, error) {
+	dir = filepath.Clean(dir)
+	l.mu.Lock()
+	layer, ok := l.layers[dir]
+	err := l.err
+	l.mu.Unlock()
+	if ok || err != nil {
+		return layer, err
+	}
+
+	parentDir := filepath.Dir(dir)
+	if parentDir == dir {
+		return nil, fmt.Errorf("%s is outside the scanned dir", dir)
+	}
+	parent, err := l.forDir(parentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err = l.load(dir, parent)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		if l.err == nil {
+			l.err = err
+		}
+		return nil, err
+	}
+	if existing, ok := l.layers[dir]; ok {
+		return existing, nil // loaded concurrently
+	}
+	l.layers[dir] = layer
+	return layer, nil
+}
+
+// load returns the layer of dir: parent, with the config file of dir over
+// it if there is one
+func (l *configLayers) load(dir string, parent *configLayer) (*configLayer, error) {
+	path, err := findConfigFile(l.fsys, dir, dirConfigBase)
+	if err != nil || path == "" {
+		return parent, err
+	}
+	data, err := afero.ReadFile(l.fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	file, err := parseConfigFile(data, path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := file.overlay(parent.cfg, path)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	warnings, err := validationError(validateConfig(cfg, func(name string) ([]byte, error) {
+		return afero.ReadFile(l.fsys, name)
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	for _, w := range warnings {
+		if w.Source == path { // the others were reported with their own file
+			common.Warn.Printf("%s", w)
+		}
+	}
+	rules, err := compileDeleteRules(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return &configLayer{cfg: cfg, rules: rules}, nil
+}
+
+// error returns the error of the first config file that could not be used
+func (l *configLayers) error() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
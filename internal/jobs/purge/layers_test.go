@@ -0,0 +1,162 @@
+package purge
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewChangesLayersDirConfigs(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	files := map[string]string{
+		"a.log":                          "x",
+		"a.jpeg":                         "x",
+		"photos/b.jpeg":                  "x",
+		"photos/raw/c.jpeg":              "x",
+		"build/main.o":                   "x",
+		"build/run.log":                  "x",
+		"build/d.tmp":                    "x",
+		"only/e.tmp":                     "x",
+		"only/f.bak":                     "x",
+		"photos/" + DirConfigName:        `{"version": 1, "rename": {"extension_replacements": {"add": {".jpeg": ".jpe"}}}}`,
+		"photos/raw/" + DirConfigName:    `{"version": 1, "rename": {"extension_replacements": {"remove": [".jpeg"]}}}`,
+		"build/" + DirConfigName:         `{"version": 1, "delete": {"extensions": {"add": [".o"], "remove": [".log"]}}}`,
+		"only/" + DirConfigName:          `{"version": 1, "delete": {"extensions": [".bak"]}}`,
+		"unrelated/" + DirConfigName:     `{"version": 1, "delete": {"hidden_files": true}}`,
+		"unrelated/keep.txt":             "x",
+		"build/sub/" + DirConfigName:     `{"version": 1}`,
+		"build/sub/g.o":                  "x",
+		"build/sub/h.log":                "x",
+		"build/sub/nested/" + "i.o":      "x",
+		"build/sub/nested/" + "keep.txt": "x",
+	}
+	for name, data := range files {
+		require.NoError(t, afero.WriteFile(fsys, filepath.Join(root, name), []byte(data), 0644))
+	}
+	cfg := &Config{
+		ExtensionsToDelete:    []string{".log", ".tmp"},
+		ExtensionReplacements: map[string]string{".jpeg": ".jpg"},
+	}
+	stampSources(cfg, "/home/user/.housekeeper.json")
+
+	changes, err := previewChanges(context.Background(), fsys, root, cfg, 0, nil)
+	require.NoError(t, err)
+
+	got := make(map[string]Change)
+	for _, c := range changes {
+		if c.Type != RemoveDir {
+			rel, err := filepath.Rel(root, c.Target)
+			require.NoError(t, err)
+			got[filepath.ToSlash(rel)] = c
+		}
+	}
+	want := map[string]string{
+		"a.log":                "/home/user/.housekeeper.json",
+		"a.jpeg":               "/home/user/.housekeeper.json",
+		"photos/b.jpeg":        filepath.Join(root, "photos", DirConfigName),
+		"build/main.o":         filepath.Join(root, "build", DirConfigName),
+		"build/d.tmp":          "/home/user/.housekeeper.json",
+		"build/sub/g.o":        filepath.Join(root, "build", DirConfigName),
+		"build/sub/nested/i.o": filepath.Join(root, "build", DirConfigName),
+		"only/f.bak":           filepath.Join(root, "only", DirConfigName),
+	}
+	assert.Len(t, got, len(want), "changes: %v", got)
+	for rel, source := range want {
+		c, ok := got[rel]
+		if !assert.True(t, ok, "no change for %s", rel) {
+			continue
+		}
+		assert.Equal(t, source, c.Reason.Source, rel)
+	}
+	assert.Equal(t, filepath.Join(root, "photos", "b.jpe"), got["photos/b.jpeg"].NewName)
+	assert.NotContains(t, got, "photos/raw/c.jpeg", "the replacement is removed below raw")
+	assert.NotContains(t, got, "build/sub/h.log", "the removal is inherited by subdirectories")
+
+	// The stream honors the same layers
+	var streamed int
+	for c, err := range previewChangesSeq(context.Background(), fsys, root, cfg, 0, nil) {
+		require.NoError(t, err)
+		if c.Type != RemoveDir {
+			streamed++
+		}
+	}
+	assert.Equal(t, len(want), streamed)
+}
+
+func TestPreviewChangesDirConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "not json", data: `{`, wantErr: "invalid JSON"},
+		{name: "no version", data: `{"delete": {"extensions": [".o"]}}`, wantErr: "no version"},
+		{name: "unknown edit", data: `{"version": 1, "delete": {"extensions": {"append": [".o"]}}}`, wantErr: `unknown field "append"`},
+		{name: "excludes", data: `{"version": 1, "excludes": ["out/"]}`, wantErr: IgnoreFileName},
+		{name: "safety", data: `{"version": 1, "safety": {"max_changes": 1}}`, wantErr: "whole scan"},
+		{name: "invalid rule", data: `{"version": 1, "delete": {"rules": [{"kind": "regex", "pattern": "("}]}}`, wantErr: "invalid rule: invalid regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := afero.NewMemMapFs()
+			root := filepath.Join(string(filepath.Separator), "scan")
+			path := filepath.Join(root, "sub", DirConfigName)
+			require.NoError(t, afero.WriteFile(fsys, path, []byte(tt.data), 0644))
+			require.NoError(t, afero.WriteFile(fsys, filepath.Join(root, "sub", "a.tmp"), []byte("x"), 0644))
+
+			_, err := previewChanges(context.Background(), fsys, root, &Config{ExtensionsToDelete: []string{".tmp"}}, 0, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), path)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestStringListAndMapJSON(t *testing.T) {
+	base := []string{".log", ".tmp"}
+	lists := []struct {
+		data string
+		want []string
+	}{
+		{data: `[".o"]`, want: []string{".o"}},
+		{data: `{"add": [".o", ".tmp"]}`, want: []string{".log", ".tmp", ".o"}},
+		{data: `{"remove": [".log"]}`, want: []string{".tmp"}},
+		{data: `{"replace": [".a"], "add": [".o"], "remove": [".a"]}`, want: []string{".o"}},
+	}
+	for _, tt := range lists {
+		var l StringList
+		require.NoError(t, json.Unmarshal([]byte(tt.data), &l), tt.data)
+		assert.Equal(t, tt.want, l.apply(base), tt.data)
+
+		data, err := json.Marshal(l)
+		require.NoError(t, err)
+		assert.JSONEq(t, tt.data, string(data))
+	}
+	assert.Equal(t, []string{".log", ".tmp"}, base, "the inherited list is left as is")
+
+	inherited := map[string]string{".htm": ".html", ".jpeg": ".jpg"}
+	maps := []struct {
+		data string
+		want map[string]string
+	}{
+		{data: `{".tif": ".tiff"}`, want: map[string]string{".tif": ".tiff"}},
+		{data: `{"add": {".tif": ".tiff"}}`, want: map[string]string{".htm": ".html", ".jpeg": ".jpg", ".tif": ".tiff"}},
+		{data: `{"remove": [".htm"]}`, want: map[string]string{".jpeg": ".jpg"}},
+		{data: `{"replace": {".mpeg": ".mpg"}}`, want: map[string]string{".mpeg": ".mpg"}},
+	}
+	for _, tt := range maps {
+		var m StringMap
+		require.NoError(t, json.Unmarshal([]byte(tt.data), &m), tt.data)
+		assert.Equal(t, tt.want, m.apply(inherited), tt.data)
+	}
+	assert.Len(t, inherited, 2, "the inherited map is left as is")
+
+	var l StringList
+	assert.Error(t, json.Unmarshal([]byte(`{"add": ".o"}`), &l))
+}
@@ -0,0 +1,85 @@
+package purge
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// SettingOrigin tells which file a setting came from
+type SettingOrigin struct {
+	Setting string `json:"setting"` // as named in the config file, e.g. "delete.extensions"
+	Value   string `json:"value"`
+	Source  string `json:"source"` // file, BuiltinSource, or a flag
+}
+
+// Origins lists the settings of cfg that are set, in config file order,
+// with where each came from. List and map settings have an entry per item.
+func (cfg *Config) Origins() []SettingOrigin {
+	var origins []SettingOrigin
+	add := func(setting, value, id string) {
+		origins = append(origins, SettingOrigin{Setting: setting, Value: value, Source: cfg.Sources[id]})
+	}
+
+	for _, ext := range cfg.ExtensionsToDelete {
+		add("delete.extensions", ext, shorthandID("extensions_to_delete", ext))
+	}
+	for _, prefix := range cfg.PrefixesToDelete {
+		add("delete.prefixes", prefix, shorthandID("prefixes_to_delete", prefix))
+	}
+	if cfg.DeleteHiddenFiles {
+		add("delete.hidden_files", "true", "delete_hidden_files")
+	}
+	for i, r := range cfg.DeleteRules {
+		id := r.ID
+		if id == "" {
+			id = fmt.Sprintf("delete_rules[%d]", i)
+		}
+		origins = append(origins, SettingOrigin{
+			Setting: "delete.rules",
+			Value:   fmt.Sprintf("%s: %s %q", id, r.Kind, r.Pattern),
+			Source:  r.Source,
+		})
+	}
+
+	froms := make([]string, 0, len(cfg.ExtensionReplacements))
+	for from := range cfg.ExtensionReplacements {
+		froms = append(froms, from)
+	}
+	slices.Sort(froms)
+	for _, from := range froms {
+		add("rename.extension_replacements", from+" → "+cfg.ExtensionReplacements[from], shorthandID("extension_replacements", from))
+	}
+	if cfg.RenameCollisions != "" {
+		add("rename.collisions", string(cfg.RenameCollisions), "rename_collisions")
+	}
+
+	for _, pattern := range cfg.Excludes {
+		add("excludes", pattern, shorthandID("excludes", pattern))
+	}
+
+	if cfg.FingerprintHash {
+		add("safety.fingerprint_hash", "true", "fingerprint_hash")
+	}
+	if cfg.Safety.MaxChanges != 0 {
+		add("safety.max_changes", strconv.Itoa(cfg.Safety.MaxChanges), "safety.max_changes")
+	}
+	if cfg.Safety.MaxDeleteBytes != 0 {
+		add("safety.max_delete_bytes", cfg.Safety.MaxDeleteBytes.String(), "safety.max_delete_bytes")
+	}
+
+	l := cfg.Logging
+	if l.ToFile != nil {
+		add("logging.to_file", strconv.FormatBool(*l.ToFile), "logging.to_file")
+	}
+	if l.Path != "" {
+		add("logging.path", l.Path, "logging.path")
+	}
+	if l.Debug != nil {
+		add("logging.debug", strconv.FormatBool(*l.Debug), "logging.debug")
+	}
+	if l.Console != nil {
+		add("logging.also_print_to_console", strconv.FormatBool(*l.Console), "logging.also_print_to_console")
+	}
+	return origins
+}
@@ -0,0 +1,145 @@
+package purge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// PlanVersion is the version of the plan document written by WritePlan
+const PlanVersion = 1
+
+// PlanFile is a serialized plan that can be reviewed, edited and applied later
+type PlanFile struct {
+	Version    int       `json:"version"`
+	Root       string    `json:"root"`        // absolute path of the scanned dir
+	ConfigHash string    `json:"config_hash"` // sha256 of the config the plan was made with
+	CreatedAt  time.Time `json:"created_at"`
+	Changes    []Change  `json:"changes"`
+}
+
+// PlanFile runs a dry run and wraps the changes in a plan document
+func (j *Job) PlanFile(ctx context.Context) (*PlanFile, error) {
+	changes, err := j.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlanFile(j.Dir, j.Cfg, changes)
+}
+
+// NewPlanFile wraps changes planned for root with cfg in a plan document.
+// Paths are made absolute so the plan can be applied from any directory.
+func NewPlanFile(root string, cfg *Config, changes []Change) (*PlanFile, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := ConfigHash(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := make([]Change, 0, len(changes))
+	for _, c := range changes {
+		if c.Target, err = filepath.Abs(c.Target); err != nil {
+			return nil, err
+		}
+		if c.NewName != "" {
+			if c.NewName, err = filepath.Abs(c.NewName); err != nil {
+				return nil, err
+			}
+		}
+		planned = append(planned, c)
+	}
+
+	return &PlanFile{
+		Version:    PlanVersion,
+		Root:       abs,
+		ConfigHash: hash,
+		CreatedAt:  timeNow().UTC(),
+		Changes:    planned,
+	}, nil
+}
+
+// ConfigHash returns a stable hash of cfg, used to tell which config a plan
+// was made with
+func ConfigHash(cfg *Config) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// ConfigChanged reports whether cfg differs from the config the plan was
+// made with. Plans without a config hash, e.g. written by hand, never do.
+func (p *PlanFile) ConfigChanged(cfg *Config) (bool, error) {
+	if p.ConfigHash == "" {
+		return false, nil
+	}
+	hash, err := ConfigHash(cfg)
+	if err != nil {
+		return false, err
+	}
+	return hash != p.ConfigHash, nil
+}
+
+// WritePlan writes a plan document as indented JSON
+func WritePlan(path string, plan *PlanFile) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := AppFs.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return afero.WriteFile(AppFs, path, append(data, '\n'), 0644)
+}
+
+// ReadPlan reads a plan document written by WritePlan
+func ReadPlan(path string) (*PlanFile, error) {
+	data, err := afero.ReadFile(AppFs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan PlanFile
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("parsing plan %s: %w", path, err)
+	}
+	if plan.Version < 1 || plan.Version > PlanVersion {
+		return nil, fmt.Errorf("plan %s has unsupported version %d (want %d)", path, plan.Version, PlanVersion)
+	}
+	for i, c := range plan.Changes {
+		if err := validatePlannedChange(c); err != nil {
+			return nil, fmt.Errorf("plan %s: change %d: %w", path, i+1, err)
+		}
+	}
+	return &plan, nil
+}
+
+// validatePlannedChange rejects hand-edited changes that cannot be applied
+func validatePlannedChange(c Change) error {
+	if c.Target == "" {
+		return fmt.Errorf("missing target")
+	}
+	switch c.Type {
+	case DeleteFile, RemoveDir:
+		return nil
+	case RenameFile:
+		if c.NewName == "" {
+			return fmt.Errorf("rename of %s has no new_name", c.Target)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown change type %q", c.Type)
+}
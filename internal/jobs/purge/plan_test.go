@@ -0,0 +1,107 @@
+package purge
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanFileRoundTrip(t *testing.T) {
+	fs := useMemFs(t)
+	originalNow := timeNow
+	timeNow = func() time.Time { return time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { timeNow = originalNow })
+
+	root := filepath.Join(string(filepath.Separator), "scan")
+	cfg := &Config{ExtensionsToDelete: []string{".tmp"}}
+	changes := []Change{
+		{Type: DeleteFile, Target: filepath.Join(root, "a.tmp"), Reason: &Reason{RuleID: "extensions_to_delete:.tmp", Kind: RuleSuffix, Pattern: ".tmp"}},
+		{Type: RenameFile, Target: filepath.Join(root, "b.JPG"), NewName: filepath.Join(root, "b.jpg")},
+	}
+
+	plan, err := NewPlanFile(root, cfg, changes)
+	require.NoError(t, err)
+	assert.Equal(t, PlanVersion, plan.Version)
+	assert.Equal(t, root, plan.Root)
+	assert.Contains(t, plan.ConfigHash, "sha256:")
+
+	path := filepath.Join(string(filepath.Separator), "plans", "plan.json")
+	require.NoError(t, WritePlan(path, plan))
+	exists, _ := afero.Exists(fs, path)
+	require.True(t, exists)
+
+	got, err := ReadPlan(path)
+	require.NoError(t, err)
+	assert.Equal(t, plan, got)
+}
+
+func TestPlanFileMakesPathsAbsolute(t *testing.T) {
+	plan, err := NewPlanFile(".", &Config{}, []Change{
+		{Type: RenameFile, Target: "a.JPG", NewName: "a.jpg"},
+	})
+	require.NoError(t, err)
+
+	wd, err := filepath.Abs(".")
+	require.NoError(t, err)
+	assert.Equal(t, wd, plan.Root)
+	assert.Equal(t, filepath.Join(wd, "a.JPG"), plan.Changes[0].Target)
+	assert.Equal(t, filepath.Join(wd, "a.jpg"), plan.Changes[0].NewName)
+}
+
+func TestConfigHashChangesWithConfig(t *testing.T) {
+	a, err := ConfigHash(&Config{ExtensionsToDelete: []string{".tmp"}})
+	require.NoError(t, err)
+	b, err := ConfigHash(&Config{ExtensionsToDelete: []string{".tmp"}})
+	require.NoError(t, err)
+	c, err := ConfigHash(&Config{ExtensionsToDelete: []string{".bak"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestPlanConfigChanged(t *testing.T) {
+	cfg := &Config{ExtensionsToDelete: []string{".tmp"}}
+	hash, err := ConfigHash(cfg)
+	require.NoError(t, err)
+	plan := &PlanFile{ConfigHash: hash}
+
+	changed, err := plan.ConfigChanged(cfg)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = plan.ConfigChanged(&Config{ExtensionsToDelete: []string{".bak"}})
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = (&PlanFile{}).ConfigChanged(cfg)
+	require.NoError(t, err)
+	assert.False(t, changed, "plans without a hash match any config")
+}
+
+func TestReadPlanErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid json", data: `{"version": 1,`},
+		{name: "missing version", data: `{"changes": []}`},
+		{name: "future version", data: `{"version": 99, "changes": []}`},
+		{name: "unknown change type", data: `{"version": 1, "changes": [{"type": "chmod", "target": "/a"}]}`},
+		{name: "rename without new name", data: `{"version": 1, "changes": [{"type": "rename_file", "target": "/a"}]}`},
+		{name: "missing target", data: `{"version": 1, "changes": [{"type": "delete_file"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := useMemFs(t)
+			require.NoError(t, afero.WriteFile(fs, "plan.json", []byte(tt.data), 0644))
+			_, err := ReadPlan("plan.json")
+			assert.Error(t, err)
+		})
+	}
+}
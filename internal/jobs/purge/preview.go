@@ -1,46 +1,129 @@
 package purge
 
 import (
-	"fmt"
+	"context"
 	"io/fs"
 	"path/filepath"
+
+	"github.com/spf13/afero"
+
+	"housekeeper/internal/common"
 )
 
 // RunDry performs housekeeping checks but does not modify anything.
 func PreviewChanges(directory string, cfg *Config) ([]Change, error) {
-	var changes []Change
+	return PreviewChangesContext(context.Background(), directory, cfg)
+}
 
-	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			fmt.Printf("[ERROR] Accessing %s: %v\n", path, err)
-			return nil
-		}
-		if d.IsDir() {
-			return nil
-		}
+// PreviewChangesContext is PreviewChanges with a context. The walk stops
+// once ctx is done and the context's error is returned without changes.
+func PreviewChangesContext(ctx context.Context, directory string, cfg *Config) ([]Change, error) {
+	return previewChanges(ctx, AppFs, directory, cfg, 0, nil)
+}
 
-		// 1. Check if file should be deleted
-		if c := checkDelete(path, cfg.ExtensionsToDelete); c != nil {
-			changes = append(changes, *c)
-		} else {
-			// 2. If not deleting, try renaming (replacement > lowercase)
-			if c := computeRename(path, cfg.ExtensionReplacements); c != nil {
-				changes = append(changes, *c)
-			}
-		}
+// previewChanges plans the changes for directory on fsys, reading up to
+// workers directories at once (DefaultWalkWorkers if 0), and reports its
+// progress to obs if not nil. The result is in the same order as a serial
+// walk.
+func previewChanges(ctx context.Context, fsys afero.Fs, directory string, cfg *Config, workers int, obs ProgressObserver) ([]Change, error) {
+	p, err := newPlanner(fsys, directory, cfg, obs)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	tree, err := walkDirs(ctx, fsys, directory, p.ex, workers, p.prog, p.visit)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.layers.error(); err != nil {
+		return nil, err
+	}
+
+	changes, err := resolveCollisions(fsys, tree.changes(), p.policy)
 	if err != nil {
 		return nil, err
 	}
 
-	emptyDirs, err := findEmptyDirs(directory, changes)
+	emptyDirs, err := tree.emptyDirs(changes)
 	if err != nil {
 		return nil, err
 	}
 	changes = append(changes, emptyDirs...)
+	p.prog.planned(len(changes))
 
 	return changes, nil
 }
 
+// planner holds what planning a tree takes, for previewChanges and
+// previewChangesSeq
+type planner struct {
+	fsys      afero.Fs
+	directory string
+	cfg       *Config
+	layers    *configLayers
+	ex        *excluder
+	policy    CollisionPolicy
+	prog      *progress
+}
+
+func newPlanner(fsys afero.Fs, directory string, cfg *Config, obs ProgressObserver) (*planner, error) {
+	layers, err := newConfigLayers(fsys, directory, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ex, err := newExcluder(fsys, directory, cfg.Excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := ParseCollisionPolicy(string(cfg.RenameCollisions))
+	if err != nil {
+		return nil, err
+	}
+
+	return &planner{
+		fsys:      fsys,
+		directory: directory,
+		cfg:       cfg,
+		layers:    layers,
+		ex:        ex,
+		policy:    policy,
+		prog:      newProgress(obs),
+	}, nil
+}
+
+// visit plans the change for a single file, if any, with the config in
+// effect for its directory. It is called concurrently by the walk. A config
+// file that cannot be used is kept in p.layers, to fail the plan.
+func (p *planner) visit(path string, info fs.FileInfo) *Change {
+	rel, err := filepath.Rel(p.directory, path)
+	if err != nil {
+		common.Error.Printf("Accessing %s: %v", path, err)
+		return nil
+	}
+	layer, err := p.layers.forDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+
+	// 1. Check if file should be deleted
+	c := checkDelete(path, rel, info, layer.rules)
+	if c == nil {
+		// 2. If not deleting, try renaming (replacement > lowercase)
+		if c = computeRename(path, layer.cfg.ExtensionReplacements); c != nil {
+			c.Reason.Source = layer.cfg.Sources[c.Reason.RuleID]
+		}
+	}
+	if c == nil {
+		return nil
+	}
+
+	// 3. Fingerprint the target so apply can detect drift
+	if c.Fingerprint, err = newFingerprint(p.fsys, path, info, p.cfg.FingerprintHash); err != nil {
+		common.Error.Printf("Fingerprinting %s: %v", path, err)
+		return nil
+	}
+	p.prog.changeFound(*c)
+	return c
+}
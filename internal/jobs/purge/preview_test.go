@@ -86,6 +86,7 @@ func TestPreviewChanges(t *testing.T) {
 			cfg: &Config{
 				ExtensionsToDelete:    []string{".tmp"},
 				ExtensionReplacements: map[string]string{".txt": ".bak"},
+				DeleteHiddenFiles:     true,
 			},
 			expected: []Change{
 				{Type: DeleteFile, Target: filepath.Join("nested_mixed_content_dir", "subdir_w_mixed_content", ".dot_temp_file_2.txt")},
@@ -116,10 +117,6 @@ func TestPreviewChanges(t *testing.T) {
 				ExtensionReplacements: map[string]string{},
 			},
 			expected: []Change{
-				{Type: DeleteFile, Target: filepath.Join("nested_mixed_content_dir", "subdir_w_mixed_content", ".dot_temp_file_2.txt")},
-				{Type: DeleteFile, Target: filepath.Join("nested_mixed_content_dir", "subdir_w_temp_files", ".dot_temp_file_3.txt")},
-				{Type: DeleteFile, Target: filepath.Join("top_lvl_mixed_temp_files_dir", ".dot_temp_file.txt")},
-				{Type: DeleteFile, Target: filepath.Join("top_lvl_only_temp_files_dir", ".dot_temp_file_4.txt")},
 				{Type: RemoveDir, Target: filepath.Join("nested_empty_dir", "empty_subdir_a")},
 				{Type: RemoveDir, Target: filepath.Join("nested_empty_dir", "empty_subdir_b", "empty_subdir_b1")},
 				{Type: RemoveDir, Target: filepath.Join("nested_empty_dir", "empty_subdir_b")},
@@ -0,0 +1,103 @@
+package purge
+
+import "sync"
+
+// ProgressKind identifies what a ProgressEvent reports
+type ProgressKind string
+
+const (
+	ProgressDirScanned    ProgressKind = "dir_scanned"    // a directory was read
+	ProgressFileScanned   ProgressKind = "file_scanned"   // a file was checked against the rules
+	ProgressChangeFound   ProgressKind = "change_found"   // a rule planned a change for a file
+	ProgressPlanned       ProgressKind = "planned"        // planning finished
+	ProgressChangeApplied ProgressKind = "change_applied" // a change was applied
+	ProgressChangeFailed  ProgressKind = "change_failed"  // a change failed or its target drifted
+)
+
+// ProgressTotals are the running counts of a plan or an apply
+type ProgressTotals struct {
+	DirsScanned    int   `json:"dirs_scanned"`
+	FilesScanned   int   `json:"files_scanned"`
+	ChangesFound   int   `json:"changes_found"`
+	ChangesApplied int   `json:"changes_applied"`
+	ChangesFailed  int   `json:"changes_failed"`
+	BytesReclaimed int64 `json:"bytes_reclaimed"`
+}
+
+// ProgressEvent reports one step of a plan or an apply
+type ProgressEvent struct {
+	Kind   ProgressKind   `json:"kind"`
+	Path   string         `json:"path,omitempty"`   // the dir or file the event is about
+	Change *Change        `json:"change,omitempty"` // for change events
+	Err    error          `json:"-"`                // why a change failed
+	Bytes  int64          `json:"bytes,omitempty"`  // reclaimed by an applied change
+	Totals ProgressTotals `json:"totals"`           // including this event
+}
+
+// ProgressObserver receives progress events. Events are delivered one at a
+// time, even when planning or applying runs concurrently, so observers need
+// no locking of their own; they should return quickly though.
+type ProgressObserver interface {
+	Progress(ProgressEvent)
+}
+
+// ProgressFunc adapts a function to a ProgressObserver
+type ProgressFunc func(ProgressEvent)
+
+func (f ProgressFunc) Progress(e ProgressEvent) { f(e) }
+
+// progress keeps the totals for an observer and serializes its events. A
+// nil *progress reports nothing.
+type progress struct {
+	mu     sync.Mutex
+	obs    ProgressObserver
+	totals ProgressTotals
+}
+
+func newProgress(obs ProgressObserver) *progress {
+	if obs == nil {
+		return nil
+	}
+	return &progress{obs: obs}
+}
+
+// emit updates the totals with count and delivers e
+func (p *progress) emit(e ProgressEvent, count func(*ProgressTotals)) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count(&p.totals)
+	e.Totals = p.totals
+	p.obs.Progress(e)
+}
+
+func (p *progress) dirScanned(dir string) {
+	p.emit(ProgressEvent{Kind: ProgressDirScanned, Path: dir}, func(t *ProgressTotals) { t.DirsScanned++ })
+}
+
+func (p *progress) fileScanned(path string) {
+	p.emit(ProgressEvent{Kind: ProgressFileScanned, Path: path}, func(t *ProgressTotals) { t.FilesScanned++ })
+}
+
+func (p *progress) changeFound(c Change) {
+	p.emit(ProgressEvent{Kind: ProgressChangeFound, Path: c.Target, Change: &c}, func(t *ProgressTotals) { t.ChangesFound++ })
+}
+
+// planned reports the final number of changes, which also counts those
+// added when resolving collisions and the directories left empty
+func (p *progress) planned(changes int) {
+	p.emit(ProgressEvent{Kind: ProgressPlanned}, func(t *ProgressTotals) { t.ChangesFound = changes })
+}
+
+func (p *progress) changeApplied(c Change, bytes int64) {
+	p.emit(ProgressEvent{Kind: ProgressChangeApplied, Path: c.Target, Change: &c, Bytes: bytes}, func(t *ProgressTotals) {
+		t.ChangesApplied++
+		t.BytesReclaimed += bytes
+	})
+}
+
+func (p *progress) changeFailed(c Change, err error) {
+	p.emit(ProgressEvent{Kind: ProgressChangeFailed, Path: c.Target, Change: &c, Err: err}, func(t *ProgressTotals) { t.ChangesFailed++ })
+}
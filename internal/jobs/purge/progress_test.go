@@ -0,0 +1,94 @@
+package purge
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobReportsPlanProgress(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	buildWalkTestTree(t, fsys, root)
+
+	var events []ProgressEvent
+	job := &Job{
+		Dir:      root,
+		Cfg:      &Config{ExtensionsToDelete: []string{".tmp"}},
+		Fs:       fsys,
+		Workers:  8,
+		Progress: ProgressFunc(func(e ProgressEvent) { events = append(events, e) }),
+	}
+	changes, err := job.Plan(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+
+	kinds := make(map[ProgressKind]int)
+	for i, e := range events {
+		kinds[e.Kind]++
+		if i > 0 {
+			prev := events[i-1].Totals
+			assert.GreaterOrEqual(t, e.Totals.FilesScanned, prev.FilesScanned, "totals only grow")
+		}
+	}
+
+	last := events[len(events)-1]
+	assert.Equal(t, ProgressPlanned, last.Kind)
+	assert.Equal(t, len(changes), last.Totals.ChangesFound)
+	assert.Equal(t, kinds[ProgressDirScanned], last.Totals.DirsScanned)
+	assert.Equal(t, kinds[ProgressFileScanned], last.Totals.FilesScanned)
+
+	// d3/s2 is excluded by an ignore file, so neither it nor its subdirs are read
+	assert.Equal(t, 1+8+8*6*3-3, last.Totals.DirsScanned)
+
+	var renamesAndDeletes int
+	for _, c := range changes {
+		if c.Type != RemoveDir {
+			renamesAndDeletes++
+		}
+	}
+	assert.Equal(t, renamesAndDeletes, kinds[ProgressChangeFound])
+}
+
+func TestApplyReportsProgress(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	require.NoError(t, afero.WriteFile(fsys, "/scan/a.tmp", make([]byte, 100), 0644))
+	require.NoError(t, afero.WriteFile(fsys, "/scan/b.tmp", make([]byte, 20), 0644))
+	require.NoError(t, afero.WriteFile(fsys, "/scan/c.JPG", nil, 0644))
+	changes := []Change{
+		{Type: DeleteFile, Target: "/scan/a.tmp"},
+		{Type: DeleteFile, Target: "/scan/missing.tmp"},
+		{Type: DeleteFile, Target: "/scan/b.tmp"},
+		{Type: RenameFile, Target: "/scan/c.JPG", NewName: "/scan/c.jpg"},
+	}
+
+	var events []ProgressEvent
+	_, err := ApplyAllWithOptions(changes, ApplyOptions{
+		Fs:          fsys,
+		Concurrency: 4,
+		Progress:    ProgressFunc(func(e ProgressEvent) { events = append(events, e) }),
+	})
+	require.Error(t, err)
+	require.Len(t, events, 4)
+
+	var failed *ProgressEvent
+	for i, e := range events {
+		if e.Kind == ProgressChangeFailed {
+			failed = &events[i]
+		}
+	}
+	require.NotNil(t, failed)
+	assert.Equal(t, "/scan/missing.tmp", failed.Path)
+	assert.Error(t, failed.Err)
+
+	totals := events[len(events)-1].Totals
+	assert.Equal(t, ProgressTotals{ChangesApplied: 3, ChangesFailed: 1, BytesReclaimed: 120}, totals)
+}
@@ -12,7 +12,12 @@ func computeRename(path string, replacements map[string]string) *Change {
     // Try replacement first
     if newExt, ok := replacements[ext]; ok {
         newPath := filepath.Join(filepath.Dir(path), base+newExt)
-        return &Change{Type: RenameFile, Target: path, NewName: newPath}
+        return &Change{
+            Type:    RenameFile,
+            Target:  path,
+            NewName: newPath,
+            Reason:  &Reason{RuleID: shorthandID("extension_replacements", ext), Kind: RuleReplacement, Pattern: ext},
+        }
     }
 
     // Fallback to lowercase if no replacement
@@ -20,7 +25,12 @@ func computeRename(path string, replacements map[string]string) *Change {
     lowerExt := strings.ToLower(originalExt)
     if originalExt != lowerExt {
         newPath := filepath.Join(filepath.Dir(path), base+lowerExt)
-        return &Change{Type: RenameFile, Target: path, NewName: newPath}
+        return &Change{
+            Type:    RenameFile,
+            Target:  path,
+            NewName: newPath,
+            Reason:  &Reason{RuleID: string(RuleLowercase), Kind: RuleLowercase, Pattern: originalExt},
+        }
     }
 
     return nil
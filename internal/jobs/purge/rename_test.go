@@ -12,13 +12,7 @@ func init() {
 	println("Working dir:", wd)
 }
 
-var testConfig = func() *Config {
-	cfg, err := LoadConfigWithOptions(LoadConfigOptions{})
-	if err != nil {
-		panic("can't load test config: " + err.Error())
-	}
-	return cfg
-}()
+var testConfig = DefaultConfig()
 
 func TestAutoGeneratedCases(t *testing.T) {
 	for fromExt, toExt := range testConfig.ExtensionReplacements {
@@ -101,3 +95,23 @@ func assertRename(t *testing.T, input, want string, replacements map[string]stri
 	}
 }
 
+
+func TestComputeRenameReason(t *testing.T) {
+	replacements := map[string]string{".jpeg": ".jpg"}
+
+	c := computeRename("/photo.JPEG", replacements)
+	if c == nil || c.Reason == nil {
+		t.Fatalf("expected rename with reason, got %+v", c)
+	}
+	if c.Reason.Kind != RuleReplacement || c.Reason.RuleID != "extension_replacements:.jpeg" {
+		t.Errorf("Reason = %+v, want replacement rule for .jpeg", *c.Reason)
+	}
+
+	c = computeRename("/DATA.XML", replacements)
+	if c == nil || c.Reason == nil {
+		t.Fatalf("expected rename with reason, got %+v", c)
+	}
+	if c.Reason.Kind != RuleLowercase || c.Reason.Pattern != ".XML" {
+		t.Errorf("Reason = %+v, want lowercase rule for .XML", *c.Reason)
+	}
+}
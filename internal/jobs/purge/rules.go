@@ -0,0 +1,198 @@
+package purge
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// timeNow is the reference time for age predicates, mockable in tests
+var timeNow = time.Now
+
+// deleteRule is a DeleteRule ready for matching
+type deleteRule struct {
+	DeleteRule
+	re *regexp.Regexp
+}
+
+// compileDeleteRules expands the extension and prefix shorthands into rules
+// and validates every configured rule. Rules are evaluated in the returned order.
+func compileDeleteRules(cfg *Config) ([]deleteRule, error) {
+	rules := make([]deleteRule, 0, len(cfg.ExtensionsToDelete)+len(cfg.PrefixesToDelete)+len(cfg.DeleteRules)+1)
+
+	shorthand := func(id string, kind RuleKind, pattern string) deleteRule {
+		return deleteRule{DeleteRule: DeleteRule{ID: id, Kind: kind, Pattern: pattern, Source: cfg.Sources[id]}}
+	}
+
+	for _, ext := range cfg.ExtensionsToDelete {
+		rules = append(rules, shorthand(shorthandID("extensions_to_delete", ext), RuleSuffix, ext))
+	}
+	for _, prefix := range cfg.PrefixesToDelete {
+		rules = append(rules, shorthand(shorthandID("prefixes_to_delete", prefix), RulePrefix, prefix))
+	}
+	if cfg.DeleteHiddenFiles {
+		rules = append(rules, shorthand("delete_hidden_files", RulePrefix, "."))
+	}
+
+	for i, r := range cfg.DeleteRules {
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("delete_rules[%d]", i)
+		}
+		compiled, err := compileDeleteRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("delete rule %d (%q): %w", i, r.Pattern, err)
+		}
+		rules = append(rules, compiled)
+	}
+
+	return rules, nil
+}
+
+func compileDeleteRule(r DeleteRule) (deleteRule, error) {
+	if r.Pattern == "" {
+		return deleteRule{}, fmt.Errorf("empty pattern")
+	}
+	if r.MinSize < 0 || r.MaxSize < 0 {
+		return deleteRule{}, fmt.Errorf("negative size")
+	}
+	if r.MaxSize > 0 && r.MinSize > r.MaxSize {
+		return deleteRule{}, fmt.Errorf("min_size %s exceeds max_size %s", r.MinSize, r.MaxSize)
+	}
+	if r.MtimeOlderThan < 0 || r.AtimeOlderThan < 0 || r.CtimeOlderThan < 0 {
+		return deleteRule{}, fmt.Errorf("negative age")
+	}
+
+	switch r.Kind {
+	case RuleSuffix, RulePrefix:
+		return deleteRule{DeleteRule: r}, nil
+	case RuleGlob:
+		if !doublestar.ValidatePattern(strings.TrimPrefix(r.Pattern, "/")) {
+			return deleteRule{}, fmt.Errorf("invalid glob")
+		}
+		return deleteRule{DeleteRule: r}, nil
+	case RuleRegex:
+		// Anchor so the pattern has to describe the whole file name
+		re, err := regexp.Compile("^(?:" + r.Pattern + ")$")
+		if err != nil {
+			return deleteRule{}, fmt.Errorf("invalid regex: %w", err)
+		}
+		return deleteRule{DeleteRule: r, re: re}, nil
+	default:
+		return deleteRule{}, fmt.Errorf("unknown rule kind %q", r.Kind)
+	}
+}
+
+// shorthandID names a rule that comes from a plain list or map entry in the
+// config, such as "extensions_to_delete:.tmp"
+func shorthandID(key, value string) string {
+	return key + ":" + value
+}
+
+func (r *deleteRule) reason() *Reason {
+	return &Reason{RuleID: r.ID, Kind: r.Kind, Pattern: r.Pattern, Conditions: r.conditions(), Source: r.Source}
+}
+
+// conditions describes the size and age predicates, e.g. "size >= 1GiB, mtime older than 30d"
+func (r *deleteRule) conditions() string {
+	var parts []string
+	if r.MinSize > 0 {
+		parts = append(parts, "size >= "+r.MinSize.String())
+	}
+	if r.MaxSize > 0 {
+		parts = append(parts, "size <= "+r.MaxSize.String())
+	}
+	for _, age := range []struct {
+		name string
+		age  Age
+	}{{"mtime", r.MtimeOlderThan}, {"atime", r.AtimeOlderThan}, {"ctime", r.CtimeOlderThan}} {
+		if age.age > 0 {
+			parts = append(parts, age.name+" older than "+age.age.String())
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// needsInfo reports whether matching requires the file's metadata
+func (r *deleteRule) needsInfo() bool {
+	return r.MinSize > 0 || r.MaxSize > 0 || r.MtimeOlderThan > 0 || r.AtimeOlderThan > 0 || r.CtimeOlderThan > 0
+}
+
+// String describes the reason for humans, e.g.
+// suffix ".tmp" (rule extensions_to_delete:.tmp from .housekeeper.json)
+func (r Reason) String() string {
+	if r.Kind == RuleCollision {
+		return fmt.Sprintf("rename collision (%s): %s", r.Pattern, r.Conditions)
+	}
+	var b strings.Builder
+	b.WriteString(string(r.Kind))
+	if r.Pattern != "" {
+		fmt.Fprintf(&b, " %q", r.Pattern)
+	}
+	if r.Conditions != "" {
+		fmt.Fprintf(&b, " when %s", r.Conditions)
+	}
+	fmt.Fprintf(&b, " (rule %s", r.RuleID)
+	if r.Source != "" {
+		fmt.Fprintf(&b, " from %s", r.Source)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// matches reports whether the rule selects rel, a slash-separated path
+// relative to the scanned root. info may be nil if the rule does not need it.
+func (r *deleteRule) matches(rel string, info fs.FileInfo) bool {
+	return r.matchesPattern(rel) && r.matchesPredicates(info)
+}
+
+func (r *deleteRule) matchesPredicates(info fs.FileInfo) bool {
+	if !r.needsInfo() {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+
+	if r.MinSize > 0 && info.Size() < int64(r.MinSize) {
+		return false
+	}
+	if r.MaxSize > 0 && info.Size() > int64(r.MaxSize) {
+		return false
+	}
+
+	now := timeNow()
+	olderThan := func(t time.Time, age Age) bool {
+		return age == 0 || now.Sub(t) >= time.Duration(age)
+	}
+	atime, ctime := fileTimes(info)
+	return olderThan(info.ModTime(), r.MtimeOlderThan) &&
+		olderThan(atime, r.AtimeOlderThan) &&
+		olderThan(ctime, r.CtimeOlderThan)
+}
+
+func (r *deleteRule) matchesPattern(rel string) bool {
+	name := path.Base(rel)
+
+	switch r.Kind {
+	case RuleSuffix:
+		return strings.HasSuffix(strings.ToLower(name), r.Pattern)
+	case RulePrefix:
+		return strings.HasPrefix(name, r.Pattern)
+	case RuleGlob:
+		// Patterns without a slash match the file name at any depth
+		if !strings.Contains(r.Pattern, "/") {
+			ok, _ := doublestar.Match(r.Pattern, name)
+			return ok
+		}
+		ok, _ := doublestar.Match(strings.TrimPrefix(r.Pattern, "/"), rel)
+		return ok
+	case RuleRegex:
+		return r.re.MatchString(name)
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package purge
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeleteRuleMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		rule DeleteRule
+		rel  string
+		want bool
+	}{
+		{
+			name: "glob without slash matches file name at any depth",
+			rule: DeleteRule{Kind: RuleGlob, Pattern: "Thumbs.db"},
+			rel:  "photos/2024/Thumbs.db",
+			want: true,
+		},
+		{
+			name: "glob with office lock prefix",
+			rule: DeleteRule{Kind: RuleGlob, Pattern: "~$*.docx"},
+			rel:  "reports/~$budget.docx",
+			want: true,
+		},
+		{
+			name: "glob with office lock prefix ignores other extensions",
+			rule: DeleteRule{Kind: RuleGlob, Pattern: "~$*.docx"},
+			rel:  "reports/~$budget.xlsx",
+			want: false,
+		},
+		{
+			name: "glob with directory matches relative path",
+			rule: DeleteRule{Kind: RuleGlob, Pattern: "Downloads/**/*.part"},
+			rel:  "Downloads/iso/debian.iso.part",
+			want: true,
+		},
+		{
+			name: "glob with directory does not match elsewhere",
+			rule: DeleteRule{Kind: RuleGlob, Pattern: "Downloads/**/*.part"},
+			rel:  "Videos/movie.part",
+			want: false,
+		},
+		{
+			name: "leading slash anchors to the root",
+			rule: DeleteRule{Kind: RuleGlob, Pattern: "/*.part"},
+			rel:  "movie.part",
+			want: true,
+		},
+		{
+			name: "regex matches whole file name",
+			rule: DeleteRule{Kind: RuleRegex, Pattern: `core\.[0-9]+`},
+			rel:  "build/core.12345",
+			want: true,
+		},
+		{
+			name: "regex is anchored",
+			rule: DeleteRule{Kind: RuleRegex, Pattern: `core\.[0-9]+`},
+			rel:  "build/hardcore.1.txt",
+			want: false,
+		},
+		{
+			name: "suffix is case-insensitive on the file name",
+			rule: DeleteRule{Kind: RuleSuffix, Pattern: ".tmp"},
+			rel:  "a/B.TMP",
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := compileDeleteRule(tt.rule)
+			if err != nil {
+				t.Fatalf("compileDeleteRule() error = %v", err)
+			}
+			if got := r.matches(tt.rel, nil); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileDeleteRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule DeleteRule
+	}{
+		{name: "invalid regex", rule: DeleteRule{Kind: RuleRegex, Pattern: "core.[0-9"}},
+		{name: "invalid glob", rule: DeleteRule{Kind: RuleGlob, Pattern: "[a-"}},
+		{name: "unknown kind", rule: DeleteRule{Kind: "fuzzy", Pattern: "x"}},
+		{name: "empty pattern", rule: DeleteRule{Kind: RuleGlob}},
+		{name: "min size above max size", rule: DeleteRule{Kind: RuleSuffix, Pattern: ".tmp", MinSize: 10, MaxSize: 5}},
+		{name: "negative age", rule: DeleteRule{Kind: RuleSuffix, Pattern: ".tmp", MtimeOlderThan: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileDeleteRules(&Config{DeleteRules: []DeleteRule{tt.rule}})
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+// fakeFileInfo is an fs.FileInfo without platform data, so every time
+// predicate falls back to ModTime
+type fakeFileInfo struct {
+	size    int64
+	modTime time.Time
+}
+
+func (f fakeFileInfo) Name() string       { return "fake" }
+func (f fakeFileInfo) Size() int64        { return f.size }
+func (f fakeFileInfo) Mode() fs.FileMode  { return 0644 }
+func (f fakeFileInfo) ModTime() time.Time { return f.modTime }
+func (f fakeFileInfo) IsDir() bool        { return false }
+func (f fakeFileInfo) Sys() any           { return nil }
+
+func TestDeleteRulePredicates(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	originalNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = originalNow })
+
+	day := 24 * time.Hour
+	tests := []struct {
+		name string
+		rule DeleteRule
+		info fs.FileInfo
+		want bool
+	}{
+		{
+			name: "old log is deleted",
+			rule: DeleteRule{Kind: RuleSuffix, Pattern: ".log", MtimeOlderThan: Age(30 * day)},
+			info: fakeFileInfo{modTime: now.Add(-31 * day)},
+			want: true,
+		},
+		{
+			name: "recent log is kept",
+			rule: DeleteRule{Kind: RuleSuffix, Pattern: ".log", MtimeOlderThan: Age(30 * day)},
+			info: fakeFileInfo{modTime: now.Add(-29 * day)},
+			want: false,
+		},
+		{
+			name: "big tmp is deleted",
+			rule: DeleteRule{Kind: RuleSuffix, Pattern: ".tmp", MinSize: 1 << 30},
+			info: fakeFileInfo{size: 2 << 30},
+			want: true,
+		},
+		{
+			name: "small tmp is kept",
+			rule: DeleteRule{Kind: RuleSuffix, Pattern: ".tmp", MinSize: 1 << 30},
+			info: fakeFileInfo{size: 1024},
+			want: false,
+		},
+		{
+			name: "above max size is kept",
+			rule: DeleteRule{Kind: RuleSuffix, Pattern: ".tmp", MaxSize: 100},
+			info: fakeFileInfo{size: 101},
+			want: false,
+		},
+		{
+			name: "atime falls back to mtime without platform data",
+			rule: DeleteRule{Kind: RuleGlob, Pattern: "cache/**", AtimeOlderThan: Age(90 * day)},
+			info: fakeFileInfo{modTime: now.Add(-100 * day)},
+			want: true,
+		},
+		{
+			name: "predicates need file info",
+			rule: DeleteRule{Kind: RuleSuffix, Pattern: ".tmp", MinSize: 1},
+			info: nil,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := compileDeleteRule(tt.rule)
+			if err != nil {
+				t.Fatalf("compileDeleteRule() error = %v", err)
+			}
+			rel := "cache/file" + tt.rule.Pattern
+			if tt.rule.Kind == RuleGlob {
+				rel = "cache/file.bin"
+			}
+			if got := r.matches(rel, tt.info); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewChangesAgeRule(t *testing.T) {
+	dir := t.TempDir()
+	oldLog := filepath.Join(dir, "old.log")
+	newLog := filepath.Join(dir, "new.log")
+	for _, f := range []string{oldLog, newLog} {
+		if err := os.WriteFile(f, []byte("log"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	if err := os.Chtimes(oldLog, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{DeleteRules: []DeleteRule{
+		{ID: "stale-logs", Kind: RuleSuffix, Pattern: ".log", MtimeOlderThan: Age(30 * 24 * time.Hour)},
+	}}
+	changes, err := PreviewChanges(dir, cfg)
+	if err != nil {
+		t.Fatalf("PreviewChanges failed: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Target != oldLog {
+		t.Fatalf("PreviewChanges() = %+v, want only %s deleted", changes, oldLog)
+	}
+	if got := changes[0].Reason.Conditions; got != "mtime older than 30d" {
+		t.Errorf("Reason.Conditions = %q", got)
+	}
+}
@@ -0,0 +1,122 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// runConcurrently calls run for every change index with up to workers
+// calls at once. A change only starts once every earlier change it depends
+// on has finished, see applyDependencies.
+func runConcurrently(changes []Change, workers int, run func(i int)) {
+	if len(changes) == 0 {
+		return
+	}
+	dependents, waits := applyDependencies(changes)
+
+	// Buffered for every change, so sends never block while holding mu
+	ready := make(chan int, len(changes))
+	for i, n := range waits {
+		if n == 0 {
+			ready <- i
+		}
+	}
+
+	var mu sync.Mutex // guards waits and remaining
+	remaining := len(changes)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(changes)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ready {
+				run(i)
+
+				mu.Lock()
+				for _, d := range dependents[i] {
+					waits[d]--
+					if waits[d] == 0 {
+						ready <- d
+					}
+				}
+				remaining--
+				if remaining == 0 {
+					close(ready)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// applyDependencies orders changes that cannot run in parallel. A change
+// waits for every earlier change that touches the same path, so a file is
+// deleted before another is renamed onto it, and a RemoveDir waits for
+// every earlier change inside its directory. Changes only ever wait for
+// earlier ones, so the serial order always satisfies the constraints.
+// It returns the dependents of every change and how many changes each
+// waits for.
+func applyDependencies(changes []Change) (dependents [][]int, waits []int) {
+	cwd, _ := os.Getwd()
+	norm := func(p string) string {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(cwd, p)
+		}
+		return filepath.Clean(p)
+	}
+
+	removedDirs := make(map[string]bool)
+	for _, c := range changes {
+		if c.Type == RemoveDir {
+			removedDirs[norm(c.Target)] = true
+		}
+	}
+
+	dependents = make([][]int, len(changes))
+	waits = make([]int, len(changes))
+	lastTouch := make(map[string]int) // path → last change touching it
+	inside := make(map[string][]int)  // removed dir → changes inside it
+
+	for i, c := range changes {
+		paths := []string{norm(c.Target)}
+		if c.Type == RenameFile {
+			paths = append(paths, norm(c.NewName))
+		}
+
+		deps := make(map[int]bool)
+		for _, p := range paths {
+			if j, ok := lastTouch[p]; ok {
+				deps[j] = true
+			}
+			if c.Type == RemoveDir {
+				for _, j := range inside[p] {
+					deps[j] = true
+				}
+			}
+			for dir := filepath.Dir(p); ; dir = filepath.Dir(dir) {
+				if removedDirs[dir] {
+					if j, ok := lastTouch[dir]; ok {
+						deps[j] = true // the dir was removed before this change
+					}
+					inside[dir] = append(inside[dir], i)
+				}
+				if parent := filepath.Dir(dir); parent == dir {
+					break
+				}
+			}
+		}
+		for _, p := range paths {
+			lastTouch[p] = i
+		}
+
+		for j := range deps {
+			dependents[j] = append(dependents[j], i)
+		}
+		waits[i] = len(deps)
+	}
+
+	return dependents, waits
+}
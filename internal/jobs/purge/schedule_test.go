@@ -0,0 +1,148 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDependencies(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "scan")
+	p := func(elem ...string) string { return filepath.Join(append([]string{root}, elem...)...) }
+
+	changes := []Change{
+		{Type: DeleteFile, Target: p("a", "x.tmp")},                                    // 0
+		{Type: DeleteFile, Target: p("b", "photo.jpg")},                                // 1
+		{Type: RenameFile, Target: p("b", "photo.JPEG"), NewName: p("b", "photo.jpg")}, // 2: after 1
+		{Type: DeleteFile, Target: p("a", "y.tmp")},                                    // 3
+		{Type: RemoveDir, Target: p("a", "sub")},                                       // 4
+		{Type: RemoveDir, Target: p("a")},                                              // 5: after 0, 3, 4
+		{Type: DeleteFile, Target: p("c.tmp")},                                         // 6
+	}
+
+	dependents, waits := applyDependencies(changes)
+	assert.Equal(t, []int{0, 0, 1, 0, 0, 3, 0}, waits)
+
+	wantDependents := [][]int{{5}, {2}, nil, {5}, {5}, nil, nil}
+	for i := range dependents {
+		slices.Sort(dependents[i])
+		assert.Equal(t, wantDependents[i], dependents[i], "dependents of change %d", i)
+	}
+}
+
+func TestApplyDependenciesRelativeTargets(t *testing.T) {
+	dir, err := filepath.Abs("dir")
+	require.NoError(t, err)
+
+	// The planner joins file targets to the scanned dir as given, but empty
+	// dirs are always absolute
+	_, waits := applyDependencies([]Change{
+		{Type: DeleteFile, Target: filepath.Join("dir", "x.tmp")},
+		{Type: RemoveDir, Target: dir},
+	})
+	assert.Equal(t, []int{0, 1}, waits)
+}
+
+func TestRunConcurrentlyRespectsDependencies(t *testing.T) {
+	var changes []Change
+	for i := range 20 {
+		dir := filepath.Join(string(filepath.Separator), "scan", fmt.Sprintf("d%d", i))
+		for j := range 10 {
+			changes = append(changes, Change{Type: DeleteFile, Target: filepath.Join(dir, fmt.Sprintf("f%d", j))})
+		}
+		changes = append(changes, Change{Type: RemoveDir, Target: dir})
+	}
+	changes = append(changes, Change{Type: RemoveDir, Target: filepath.Join(string(filepath.Separator), "scan")})
+
+	var mu sync.Mutex
+	finished := make(map[int]bool)
+	dependents, _ := applyDependencies(changes)
+
+	runConcurrently(changes, 8, func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		for j, deps := range dependents {
+			if slices.Contains(deps, i) && !finished[j] {
+				t.Errorf("change %d started before its dependency %d", i, j)
+			}
+		}
+		finished[i] = true
+	})
+	assert.Len(t, finished, len(changes))
+}
+
+func TestApplyAllConcurrentMatchesSerial(t *testing.T) {
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	root := filepath.Join(string(filepath.Separator), "scan")
+	setup := func(t *testing.T) (afero.Fs, []Change) {
+		fsys := afero.NewMemMapFs()
+		buildWalkTestTree(t, fsys, root)
+		changes, err := previewChanges(context.Background(), fsys, root, &Config{
+			ExtensionsToDelete: []string{".tmp"},
+			RenameCollisions:   CollisionKeepNewer,
+		}, 4, nil)
+		require.NoError(t, err)
+		return fsys, changes
+	}
+
+	serialFs, changes := setup(t)
+	wantResults, err := ApplyAllWithOptions(changes, ApplyOptions{Fs: serialFs})
+	wantApplied := wantResults.Applied()
+	require.NoError(t, err)
+
+	concurrentFs, changes := setup(t)
+	results, err := ApplyAllWithOptions(changes, ApplyOptions{Fs: concurrentFs, Concurrency: 8})
+	applied := results.Applied()
+	require.NoError(t, err)
+	// Both trees were planned separately, so compare without fingerprints
+	targets := func(changes []Change) []string {
+		var out []string
+		for _, c := range changes {
+			out = append(out, string(c.Type)+" "+c.Target+" "+c.NewName)
+		}
+		return out
+	}
+	assert.Equal(t, targets(wantApplied), targets(applied), "applied changes should be in input order")
+
+	var want, got []string
+	list := func(fsys afero.Fs, paths *[]string) {
+		require.NoError(t, afero.Walk(fsys, string(filepath.Separator), func(path string, _ fs.FileInfo, err error) error {
+			*paths = append(*paths, path)
+			return err
+		}))
+	}
+	list(serialFs, &want)
+	list(concurrentFs, &got)
+	assert.Equal(t, want, got)
+}
+
+func TestApplyAllConcurrentJoinsErrors(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	originalUnlockPath := UnlockPath
+	UnlockPath = func(afero.Fs, string) error { return nil }
+	t.Cleanup(func() { UnlockPath = originalUnlockPath })
+
+	require.NoError(t, afero.WriteFile(fsys, "/scan/ok.tmp", nil, 0644))
+	changes := []Change{
+		{Type: DeleteFile, Target: "/scan/missing-1.tmp"},
+		{Type: DeleteFile, Target: "/scan/ok.tmp"},
+		{Type: DeleteFile, Target: "/scan/missing-2.tmp"},
+	}
+
+	results, err := ApplyAllWithOptions(changes, ApplyOptions{Fs: fsys, Concurrency: 4})
+	require.Error(t, err)
+	assert.Regexp(t, `(?s)missing-1\.tmp.*\n.*missing-2\.tmp`, err.Error(), "errors in input order")
+	assert.Equal(t, []Change{changes[1]}, results.Applied())
+	assert.Len(t, results.Failed(), 2)
+}
@@ -0,0 +1,143 @@
+package purge
+
+import (
+	"cmp"
+	"context"
+	"iter"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// PreviewChangesSeq is PreviewChangesContext as a stream: file changes are
+// yielded as their directories are read, and the directories left empty
+// once the walk is done. Changes within a directory are in name order, but
+// directories come in the order they are read. Planning stops when the
+// caller stops iterating; errors are yielded last, with a zero Change.
+func PreviewChangesSeq(ctx context.Context, directory string, cfg *Config) iter.Seq2[Change, error] {
+	return previewChangesSeq(ctx, AppFs, directory, cfg, 0, nil)
+}
+
+// previewChangesSeq streams the changes previewChanges would plan. Only a
+// summary of every directory is kept, so memory does not grow with the
+// number of files or changes.
+func previewChangesSeq(ctx context.Context, fsys afero.Fs, directory string, cfg *Config, workers int, obs ProgressObserver) iter.Seq2[Change, error] {
+	return func(yield func(Change, error) bool) {
+		p, err := newPlanner(fsys, directory, cfg, obs)
+		if err != nil {
+			yield(Change{}, err)
+			return
+		}
+		absRoot, err := filepath.Abs(directory)
+		if err != nil {
+			yield(Change{}, err)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var mu sync.Mutex // guards dirContents and resolveErr
+		dirContents := make(map[string]map[string]bool)
+		var resolveErr error
+
+		batches := make(chan []Change)
+		var walkErr error
+		go func() {
+			defer close(batches)
+			walkErr = scanDirs(ctx, fsys, directory, p.ex, workers, p.prog, p.visit, func(dir string, entries []walkEntry) {
+				changes, children, err := p.resolveDir(entries, absRoot)
+				mu.Lock()
+				if err != nil && resolveErr == nil {
+					resolveErr = err
+					cancel()
+				}
+				dirContents[absPath(absRoot, directory, dir)] = children
+				mu.Unlock()
+
+				if len(changes) > 0 {
+					select {
+					case batches <- changes:
+					case <-ctx.Done():
+					}
+				}
+			})
+		}()
+
+		found := 0
+		for batch := range batches {
+			for _, c := range batch {
+				if !yield(c, nil) {
+					cancel()
+					for range batches {
+						// wait for the walk to stop
+					}
+					return
+				}
+				found++
+			}
+		}
+
+		// A resolve error cancels the walk, so it goes first
+		if err := cmp.Or(resolveErr, walkErr, p.layers.error()); err != nil {
+			yield(Change{}, err)
+			return
+		}
+
+		for _, c := range detectEmptyDirs(dirContents) {
+			if !yield(c, nil) {
+				return
+			}
+			found++
+		}
+		p.prog.planned(found)
+	}
+}
+
+// resolveDir resolves the rename collisions among the changes planned for
+// the entries of one directory, which is all the collisions there can be as
+// renames never leave their directory. It also returns what is left in the
+// directory once the changes are applied, for detectEmptyDirs: every
+// subdirectory, and a single surviving file if there is one.
+func (p *planner) resolveDir(entries []walkEntry, absRoot string) ([]Change, map[string]bool, error) {
+	var planned []Change
+	for _, e := range entries {
+		if e.change != nil {
+			planned = append(planned, *e.change)
+		}
+	}
+	changes, err := resolveCollisions(p.fsys, planned, p.policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deleted := make(map[string]bool)
+	for _, c := range changes {
+		if c.Type == DeleteFile {
+			deleted[c.Target] = true
+		}
+	}
+
+	children := make(map[string]bool)
+	survivor := false
+	for _, e := range entries {
+		switch {
+		case e.info.IsDir():
+			children[absPath(absRoot, p.directory, e.path)] = true
+		case !survivor && !deleted[e.path]:
+			children[absPath(absRoot, p.directory, e.path)] = false
+			survivor = true
+		}
+	}
+	return changes, children, nil
+}
+
+// absPath maps path under root to the same path under absRoot
+func absPath(absRoot, root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(absRoot, rel)
+}
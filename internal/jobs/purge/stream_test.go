@@ -0,0 +1,88 @@
+package purge
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewChangesSeqMatchesPreviewChanges(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	buildWalkTestTree(t, fsys, root)
+	require.NoError(t, afero.WriteFile(fsys, filepath.Join(root, "d0", "s1", "b.jpg"), nil, 0644))
+	cfg := &Config{ExtensionsToDelete: []string{".tmp"}, RenameCollisions: CollisionSuffix}
+
+	want, err := previewChanges(context.Background(), fsys, root, cfg, 4, nil)
+	require.NoError(t, err)
+
+	for _, workers := range []int{1, 8} {
+		var got []Change
+		for c, err := range previewChangesSeq(context.Background(), fsys, root, cfg, workers, nil) {
+			require.NoError(t, err)
+			got = append(got, c)
+		}
+		require.Len(t, got, len(want), "workers = %d", workers)
+
+		// Empty dirs come last and in the same order; file changes may
+		// come in another order
+		split := func(changes []Change) (files, dirs []Change) {
+			i := slices.IndexFunc(changes, func(c Change) bool { return c.Type == RemoveDir })
+			return changes[:i], changes[i:]
+		}
+		wantFiles, wantDirs := split(want)
+		gotFiles, gotDirs := split(got)
+		assert.Equal(t, wantDirs, gotDirs)
+
+		byTarget := func(a, b Change) int { return strings.Compare(a.Target, b.Target) }
+		slices.SortStableFunc(wantFiles, byTarget)
+		slices.SortStableFunc(gotFiles, byTarget)
+		assert.Equal(t, wantFiles, gotFiles)
+	}
+}
+
+func TestPreviewChangesSeqStopsEarly(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	buildWalkTestTree(t, fsys, root)
+
+	n := 0
+	for _, err := range previewChangesSeq(context.Background(), fsys, root, &Config{ExtensionsToDelete: []string{".tmp"}}, 4, nil) {
+		require.NoError(t, err)
+		if n++; n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, n)
+}
+
+func TestPreviewChangesSeqErrors(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	buildWalkTestTree(t, fsys, root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var errs []error
+	for c, err := range previewChangesSeq(ctx, fsys, root, &Config{}, 4, nil) {
+		if err != nil {
+			assert.Zero(t, c)
+			errs = append(errs, err)
+		}
+	}
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+
+	errs = nil
+	for _, err := range previewChangesSeq(context.Background(), fsys, "/missing", &Config{}, 4, nil) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	assert.Error(t, errs[0])
+}
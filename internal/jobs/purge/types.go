@@ -1,5 +1,11 @@
 package purge
 
+import (
+    "github.com/spf13/afero"
+
+    "housekeeper/internal/common"
+)
+
 // ChangeType represents the type of change to apply
 type ChangeType string
 
@@ -14,16 +20,109 @@ type Change struct {
     Type    ChangeType `json:"type"`
     Target  string     `json:"target"`
     NewName string     `json:"new_name,omitempty"` // only used for rename
+    Reason  *Reason    `json:"reason,omitempty"`
+
+    Fingerprint *Fingerprint `json:"fingerprint,omitempty"` // state of Target at plan time
+    Collision   *Collision   `json:"collision,omitempty"`   // set if a rename clashed with another file
 }
 
-// Config holds settings loaded from JSON files
+// Reason records which rule planned a change
+type Reason struct {
+    RuleID  string   `json:"rule_id"`
+    Kind    RuleKind `json:"kind"`
+    Pattern    string   `json:"pattern,omitempty"`
+    Conditions string   `json:"conditions,omitempty"` // size and age predicates, if any
+    Source     string   `json:"source,omitempty"`     // config file the rule came from
+}
+
+// RuleKind selects how a DeleteRule pattern is matched
+type RuleKind string
+
+const (
+    RuleSuffix RuleKind = "suffix" // case-insensitive suffix of the file name
+    RulePrefix RuleKind = "prefix" // case-sensitive prefix of the file name
+    RuleGlob   RuleKind = "glob"   // doublestar glob on the path relative to the scanned root
+    RuleRegex  RuleKind = "regex"  // RE2 regex on the file name
+)
+
+// Kinds that only appear in a Reason
+const (
+    RuleReplacement RuleKind = "extension_replacement"
+    RuleLowercase   RuleKind = "lowercase_extension"
+    RuleEmptyDir    RuleKind = "empty_dir"
+    RuleCollision   RuleKind = "rename_collision" // a collision policy turned a rename into a delete
+)
+
+// DeleteRule marks files for deletion by pattern. The optional size and age
+// predicates must all hold as well; zero values are ignored.
+type DeleteRule struct {
+    ID      string   `json:"id,omitempty"`
+    Kind    RuleKind `json:"kind"`
+    Pattern string   `json:"pattern"`
+
+    MinSize        ByteSize `json:"min_size,omitempty"`
+    MaxSize        ByteSize `json:"max_size,omitempty"`
+    MtimeOlderThan Age      `json:"mtime_older_than,omitempty"` // last modified
+    AtimeOlderThan Age      `json:"atime_older_than,omitempty"` // last accessed
+    CtimeOlderThan Age      `json:"ctime_older_than,omitempty"` // last status change
+
+    Source string `json:"-"` // file the rule was loaded from
+}
+
+// Config holds the settings loaded from a config file, see ConfigFile. Its
+// JSON form is also the settings file of the legacy layout.
 type Config struct {
-    ExtensionsToDelete    []string          `json:"extensions_to_delete"`
+    ExtensionsToDelete    []string          `json:"extensions_to_delete"` // shorthand for suffix rules
     ExtensionReplacements map[string]string `json:"extension_replacements"`
+    DeleteRules           []DeleteRule      `json:"delete_rules"`
+    PrefixesToDelete      []string          `json:"prefixes_to_delete"`  // shorthand for prefix rules
+    DeleteHiddenFiles     bool              `json:"delete_hidden_files"` // delete every dotfile; off by default
+    Excludes              []string          `json:"excludes"`            // gitignore-style patterns skipped by the walk
+    FingerprintHash       bool              `json:"fingerprint_hash"`    // also hash file contents when planning
+    RenameCollisions      CollisionPolicy   `json:"rename_collisions"`   // what to do when a rename target exists; skip by default
+    Safety                SafetyLimits      `json:"safety"`              // checked before applying
+
+    // Logging is not used for planning, so it is not part of ConfigHash
+    Logging common.LoggingSettings `json:"-"`
+
+    // Sources maps shorthand rule IDs and settings to the file they were
+    // loaded from, see Origins
+    Sources map[string]string `json:"-"`
+
+    // Warnings are the problems LoadConfig found that do not keep the
+    // config from being used, see ValidateConfig
+    Warnings []Diagnostic `json:"-"`
+}
+
+// SafetyLimits stop an apply from doing more than expected. Zero values
+// are no limit.
+type SafetyLimits struct {
+    MaxChanges     int      `json:"max_changes,omitempty"`      // changes applied at once
+    MaxDeleteBytes ByteSize `json:"max_delete_bytes,omitempty"` // total size of the files deleted at once
 }
 
-// LoadConfigOptions holds optional overrides for config paths
+// LoadConfigOptions selects the config file for LoadConfig, see FindConfig.
+// The legacy files are merged over the config file when given, or replace
+// the built-in defaults when there is no config file.
 type LoadConfigOptions struct {
-    DeleteConfigPath  string // Optional override
-    ReplaceConfigPath string // Optional override
+    Path string // Config file given explicitly
+    Dir  string // Scanned dir, searched with its ancestors for DirConfigName; the working dir if empty
+
+    DeleteConfigPath  string // Legacy extensions_to_delete.json
+    ReplaceConfigPath string // Legacy extension_replacements.json
+    RulesConfigPath   string // Legacy delete_rules.json
+    SettingsPath      string // Legacy config.json
+}
+
+// ApplyOptions holds optional settings for ApplyAllWithOptions
+type ApplyOptions struct {
+    DeleteMode    DeleteMode       // Defaults to DeleteHard
+    QuarantineDir string           // Optional override for DeleteQuarantine
+    TrashDir      string           // Optional override for DeleteTrash
+    JournalPath   string           // Optional undo journal, appended to for every applied change
+    Fs            afero.Fs         // Filesystem to apply on; defaults to AppFs
+    Strict        bool             // Abort the whole run if any target changed since it was planned
+    Concurrency   int              // Changes applied at once; 1 (serial) if 0
+    Progress      ProgressObserver // Optional, told about every applied or failed change
+    Limits        SafetyLimits     // Refuse to apply anything if the changes exceed these
 }
\ No newline at end of file
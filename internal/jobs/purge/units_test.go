@@ -0,0 +1,86 @@
+package purge
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ByteSize
+		wantErr bool
+	}{
+		{in: "1024", want: 1024},
+		{in: "500MB", want: 500 * 1000 * 1000},
+		{in: "1GiB", want: 1 << 30},
+		{in: "1.5 KiB", want: 1536},
+		{in: "2g", want: 2 << 30},
+		{in: "GiB", wantErr: true},
+		{in: "10 parsecs", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseByteSize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseByteSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "36h", want: 36 * time.Hour},
+		{in: "1.5d", want: 36 * time.Hour},
+		{in: "soon", wantErr: true},
+		{in: "xd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseAge(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAge(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if time.Duration(got) != tt.want {
+				t.Errorf("ParseAge(%q) = %v, want %v", tt.in, time.Duration(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteRuleThresholdsFromJSON(t *testing.T) {
+	data := `{"kind": "suffix", "pattern": ".tmp", "min_size": "1GiB", "max_size": 4096, "mtime_older_than": "30d"}`
+
+	var r DeleteRule
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if r.MinSize != 1<<30 || r.MaxSize != 4096 || time.Duration(r.MtimeOlderThan) != 30*24*time.Hour {
+		t.Errorf("Unmarshal() = %+v", r)
+	}
+
+	out, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var back DeleteRule
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatalf("Unmarshal(Marshal()) error = %v", err)
+	}
+	if back != r {
+		t.Errorf("round trip = %+v, want %+v", back, r)
+	}
+}
@@ -7,14 +7,12 @@ import (
 )
 
 var (
-	// Make filesystem operations mockable
-	osStat  = os.Stat
-	osChmod = os.Chmod
-	AppFs   = afero.NewOsFs()
+	// AppFs is the filesystem used when a Job or ApplyOptions does not set one
+	AppFs = afero.NewOsFs()
 
 	// Keep UnlockPath as a function but make it mockable
-	UnlockPath = func(path string) error {
-		info, err := osStat(path)
+	UnlockPath = func(fsys afero.Fs, path string) error {
+		info, err := fsys.Stat(path)
 		if err != nil {
 			return err
 		}
@@ -28,7 +26,7 @@ var (
 			return nil
 		}
 
-		return osChmod(path, targetMode)
+		return fsys.Chmod(path, targetMode)
 	}
 )
 
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/spf13/afero"
 )
 
 func TestUnlockPath(t *testing.T) {
@@ -82,7 +84,7 @@ func TestUnlockPath(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			path := tt.setup()
-			err := UnlockPath(path)
+			err := UnlockPath(afero.NewOsFs(), path)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UnlockPath() error = %v, wantErr %v", err, tt.wantErr)
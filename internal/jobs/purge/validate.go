@@ -0,0 +1,382 @@
+package purge
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Severity tells whether a Diagnostic makes the config unusable
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // the config is rejected
+	SeverityWarning Severity = "warning" // the config works, though likely not as meant
+)
+
+// Diagnostic is a problem ValidateConfig found with a setting
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Setting  string   `json:"setting"`          // as named in the config file, e.g. "delete.extensions"
+	Value    string   `json:"value,omitempty"`  // the entry at fault, e.g. ".JPEG"
+	Source   string   `json:"source,omitempty"` // file, BuiltinSource, or a flag
+	Line     int      `json:"line,omitempty"`   // line in Source, 0 if unknown
+	Message  string   `json:"message"`
+}
+
+// String describes the diagnostic for humans, e.g.
+// .housekeeper.yaml:7: warning: rename.extension_replacements ".JPEG": never matches ...
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Source != "" {
+		b.WriteString(d.Source)
+		if d.Line > 0 {
+			b.WriteString(":" + strconv.Itoa(d.Line))
+		}
+		b.WriteString(": ")
+	}
+	fmt.Fprintf(&b, "%s: %s", d.Severity, d.Setting)
+	if d.Value != "" {
+		fmt.Fprintf(&b, " %q", d.Value)
+	}
+	b.WriteString(": " + d.Message)
+	return b.String()
+}
+
+// ValidationError is the error of LoadConfig for a config with errors. It
+// holds every diagnostic, warnings included.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	var errs []string
+	for _, d := range e.Diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d.String())
+		}
+	}
+	return "invalid config: " + strings.Join(errs, "; ")
+}
+
+// validationError returns a *ValidationError if diags has errors, and the
+// warnings otherwise
+func validationError(diags []Diagnostic) ([]Diagnostic, error) {
+	var warnings []Diagnostic
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return nil, &ValidationError{Diagnostics: diags}
+		}
+		warnings = append(warnings, d)
+	}
+	return warnings, nil
+}
+
+// ValidateConfig checks cfg for invalid patterns, and for rules that do not
+// work as they read: entries missing their leading dot, extensions whose
+// case keeps them from matching, renames of files that are deleted first,
+// rules shadowed by earlier ones, and replacements that chain or cycle.
+// Diagnostics are in the order of their files and lines.
+func ValidateConfig(cfg *Config) []Diagnostic {
+	return validateConfig(cfg, os.ReadFile)
+}
+
+// validateConfig is ValidateConfig reading the files of the diagnostics
+// with readFile, to find their lines
+func validateConfig(cfg *Config, readFile func(string) ([]byte, error)) []Diagnostic {
+	v := &validator{cfg: cfg}
+	v.checkDeleteRules()
+	v.checkReplacements()
+	v.checkExcludes()
+	if _, err := ParseCollisionPolicy(string(cfg.RenameCollisions)); err != nil {
+		v.report(SeverityError, "rename.collisions", "", cfg.Sources["rename_collisions"], err.Error())
+	}
+
+	locateDiagnostics(v.diags, readFile)
+	slices.SortStableFunc(v.diags, func(a, b Diagnostic) int {
+		if a.Source != b.Source {
+			return strings.Compare(a.Source, b.Source)
+		}
+		return a.Line - b.Line
+	})
+	return v.diags
+}
+
+type validator struct {
+	cfg   *Config
+	diags []Diagnostic
+}
+
+func (v *validator) report(severity Severity, setting, value, source, format string, args ...any) {
+	v.diags = append(v.diags, Diagnostic{
+		Severity: severity,
+		Setting:  setting,
+		Value:    value,
+		Source:   source,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ruleSetting returns the config file setting and entry a compiled rule
+// comes from
+func ruleSetting(r DeleteRule) (setting, value string) {
+	switch {
+	case strings.HasPrefix(r.ID, "extensions_to_delete:"):
+		return "delete.extensions", r.Pattern
+	case strings.HasPrefix(r.ID, "prefixes_to_delete:"):
+		return "delete.prefixes", r.Pattern
+	case r.ID == "delete_hidden_files":
+		return "delete.hidden_files", ""
+	}
+	return "delete.rules", r.Pattern
+}
+
+// checkDeleteRules reports invalid and unreachable delete rules, in the
+// order compileDeleteRules evaluates them
+func (v *validator) checkDeleteRules() {
+	type rule struct {
+		deleteRule
+		setting, value string
+	}
+	var rules []rule
+
+	compiled, _ := compileDeleteRules(&Config{
+		ExtensionsToDelete: v.cfg.ExtensionsToDelete,
+		PrefixesToDelete:   v.cfg.PrefixesToDelete,
+		DeleteHiddenFiles:  v.cfg.DeleteHiddenFiles,
+		Sources:            v.cfg.Sources,
+	})
+	for _, r := range compiled {
+		setting, value := ruleSetting(r.DeleteRule)
+		rules = append(rules, rule{deleteRule: r, setting: setting, value: value})
+	}
+	for i, r := range v.cfg.DeleteRules {
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("delete_rules[%d]", i)
+		}
+		setting, value := ruleSetting(r)
+		c, err := compileDeleteRule(r)
+		if err != nil {
+			v.report(SeverityError, setting, value, r.Source, "invalid rule: %v", err)
+			continue
+		}
+		rules = append(rules, rule{deleteRule: c, setting: setting, value: value})
+	}
+
+	for i, r := range rules {
+		switch {
+		case (r.Kind == RuleSuffix || r.Kind == RulePrefix) && r.Pattern == "":
+			v.report(SeverityError, r.setting, r.value, r.Source, "empty %s, which matches every file", r.Kind)
+			continue
+		case r.Kind == RuleSuffix && !strings.HasPrefix(r.Pattern, "."):
+			v.report(SeverityWarning, r.setting, r.value, r.Source,
+				"has no leading dot, so it matches any name ending in %q, not only the extension; write %q", r.Pattern, "."+r.Pattern)
+		case r.Kind == RuleSuffix && r.Pattern != strings.ToLower(r.Pattern):
+			v.report(SeverityWarning, r.setting, r.value, r.Source,
+				"never matches, as names are lowercased before matching suffixes; write %q", strings.ToLower(r.Pattern))
+			continue
+		}
+
+		for _, earlier := range rules[:i] {
+			if shadows(earlier.deleteRule, r.deleteRule) {
+				v.report(SeverityWarning, r.setting, r.value, r.Source,
+					"never matches, as rule %s matches the same files first", earlier.ID)
+				break
+			}
+		}
+	}
+}
+
+// shadows reports whether every file later matches is matched by earlier
+func shadows(earlier, later deleteRule) bool {
+	switch {
+	case earlier.needsInfo() || earlier.Pattern == "" || earlier.Kind != later.Kind:
+		return false
+	case earlier.Kind == RuleSuffix:
+		return strings.HasSuffix(later.Pattern, earlier.Pattern)
+	case earlier.Kind == RulePrefix:
+		return strings.HasPrefix(later.Pattern, earlier.Pattern)
+	}
+	return earlier.Pattern == later.Pattern
+}
+
+// checkReplacements reports extension replacements that cannot match, that
+// rename files deleted first, and that chain or cycle
+func (v *validator) checkReplacements() {
+	repls := v.cfg.ExtensionReplacements
+	froms := make([]string, 0, len(repls))
+	for from := range repls {
+		froms = append(froms, from)
+	}
+	slices.Sort(froms)
+
+	rules, _ := compileDeleteRules(&Config{ExtensionsToDelete: v.cfg.ExtensionsToDelete, Sources: v.cfg.Sources})
+	for i, r := range v.cfg.DeleteRules {
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("delete_rules[%d]", i)
+		}
+		if c, err := compileDeleteRule(r); err == nil && r.Kind == RuleSuffix {
+			rules = append(rules, c)
+		}
+	}
+
+	const setting = "rename.extension_replacements"
+	reported := make(map[string]bool) // cycles, reported once
+	for _, from := range froms {
+		to := repls[from]
+		source := v.cfg.Sources[shorthandID("extension_replacements", from)]
+		lower := strings.ToLower(from)
+
+		switch {
+		case !strings.HasPrefix(from, "."):
+			v.report(SeverityWarning, setting, from, source,
+				"never matches, as extensions start with a dot; write %q", "."+from)
+			continue
+		case from != lower:
+			if _, ok := repls[lower]; ok {
+				v.report(SeverityWarning, setting, from, source,
+					"conflicts with %q: extensions are lowercased before lookup, so only %q is used", lower, lower)
+			} else {
+				v.report(SeverityWarning, setting, from, source,
+					"never matches, as extensions are lowercased before lookup; write %q", lower)
+			}
+			continue
+		}
+
+		if to != "" && !strings.HasPrefix(to, ".") {
+			v.report(SeverityWarning, setting, from, source,
+				"renames to %q without a dot, so file%s becomes file%s; write %q", to, from, to, "."+to)
+		}
+		for _, r := range rules {
+			if !r.needsInfo() && r.Pattern != "" && strings.HasSuffix(from, r.Pattern) {
+				v.report(SeverityWarning, setting, from, source,
+					"never renames anything, as rule %s deletes these files first", r.ID)
+				break
+			}
+		}
+
+		// Follow the replacements from the extension renamed to, as the next
+		// runs will
+		chain := []string{from}
+		cyclic := false
+		for next := strings.ToLower(to); ; next = strings.ToLower(repls[next]) {
+			if _, ok := repls[next]; !ok {
+				break
+			}
+			if i := slices.Index(chain, next); i >= 0 {
+				cyclic = true
+				if !reported[next] {
+					for _, ext := range chain[i:] {
+						reported[ext] = true
+					}
+					cycle := append(slices.Clone(chain[i:]), next)
+					v.report(SeverityError, setting, next, v.cfg.Sources[shorthandID("extension_replacements", next)],
+						"replacements form a cycle (%s), so files are renamed again on every run", strings.Join(cycle, " → "))
+				}
+				break
+			}
+			chain = append(chain, next)
+		}
+		switch {
+		case cyclic:
+		case len(chain) > 1:
+			v.report(SeverityWarning, setting, from, source,
+				"renames to %q, which the next run replaces in turn; replace %q with %q directly",
+				to, from, repls[chain[len(chain)-1]])
+		case to != strings.ToLower(to):
+			v.report(SeverityWarning, setting, from, source,
+				"renames to %q, which the next run lowercases; write %q", to, strings.ToLower(to))
+		}
+	}
+}
+
+// checkExcludes reports exclude patterns that cannot be parsed
+func (v *validator) checkExcludes() {
+	for _, pattern := range v.cfg.Excludes {
+		if _, _, err := parseIgnoreLine("", pattern); err != nil {
+			v.report(SeverityError, "excludes", pattern, v.cfg.Sources[shorthandID("excludes", pattern)], "invalid pattern: %v", err)
+		}
+	}
+}
+
+// locateDiagnostics sets the line of the diagnostics whose source is a
+// config file, where it sets their setting and value
+func locateDiagnostics(diags []Diagnostic, readFile func(string) ([]byte, error)) {
+	type parsed struct {
+		tree  any
+		lines configLines
+	}
+	files := make(map[string]*parsed)
+	for i, d := range diags {
+		if d.Source == "" || d.Source == BuiltinSource {
+			continue
+		}
+		f, ok := files[d.Source]
+		if !ok {
+			if data, err := readFile(d.Source); err == nil {
+				if tree, lines, err := parseConfigTree(data, d.Source); err == nil {
+					f = &parsed{tree: tree, lines: lines}
+				}
+			}
+			files[d.Source] = f // nil if not a readable config file, such as a flag
+		}
+		if f != nil {
+			diags[i].Line = f.lines.line(findSetting(f.tree, d.Setting, d.Value))
+		}
+	}
+}
+
+// findSetting returns where value is set under setting in a parsed config
+// tree, as a list item, a map key or a field. Legacy files name their
+// settings differently, so they are searched whole.
+func findSetting(tree any, setting, value string) string {
+	at := ""
+	node := tree
+	name, _, _ := strings.Cut(setting, "[") // rules are found by their pattern or ID
+	for _, key := range strings.Split(name, ".") {
+		obj, _ := node.(map[string]any)
+		child, ok := obj[key]
+		if !ok {
+			at, node = "", tree
+			break
+		}
+		at, node = joinSetting(at, key), child
+	}
+	if value == "" {
+		return at
+	}
+	if found, ok := findValue(node, at, value); ok {
+		return found
+	}
+	return at
+}
+
+func findValue(node any, at, value string) (string, bool) {
+	switch node := node.(type) {
+	case string:
+		return at, node == value
+	case []any:
+		for i, item := range node {
+			if found, ok := findValue(item, indexSetting(at, i), value); ok {
+				return found, true
+			}
+		}
+	case map[string]any:
+		if _, ok := node[value]; ok {
+			return joinSetting(at, value), true
+		}
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if found, ok := findValue(node[key], joinSetting(at, key), value); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,180 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfigDefaults(t *testing.T) {
+	assert.Empty(t, ValidateConfig(DefaultConfig()))
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string // "severity setting value: message prefix"
+	}{
+		{
+			name: "missing dot",
+			cfg: Config{
+				ExtensionsToDelete:    []string{"bak"},
+				ExtensionReplacements: map[string]string{"jpeg": ".jpg", ".tif": "tiff"},
+			},
+			want: []string{
+				`warning delete.extensions "bak": has no leading dot`,
+				`warning rename.extension_replacements "jpeg": never matches, as extensions start with a dot`,
+				`warning rename.extension_replacements ".tif": `,
+			},
+		},
+		{
+			name: "uppercase",
+			cfg: Config{
+				ExtensionsToDelete:    []string{".TMP"},
+				ExtensionReplacements: map[string]string{".JPEG": ".jpg"},
+			},
+			want: []string{
+				`warning delete.extensions ".TMP": never matches, as names are lowercased`,
+				`warning rename.extension_replacements ".JPEG": never matches, as extensions are lowercased`,
+			},
+		},
+		{
+			name: "case conflict",
+			cfg: Config{
+				ExtensionReplacements: map[string]string{".JPEG": ".jpe", ".jpeg": ".jpg"},
+			},
+			want: []string{`warning rename.extension_replacements ".JPEG": `},
+		},
+		{
+			name: "deleted before renamed",
+			cfg: Config{
+				ExtensionsToDelete:    []string{".tmp"},
+				ExtensionReplacements: map[string]string{".tmp": ".txt"},
+			},
+			want: []string{`warning rename.extension_replacements ".tmp": never renames anything`},
+		},
+		{
+			name: "shadowed rule",
+			cfg: Config{
+				ExtensionsToDelete: []string{".tmp", ".old.tmp"},
+			},
+			want: []string{`warning delete.extensions ".old.tmp": never matches, as rule extensions_to_delete:.tmp`},
+		},
+		{
+			name: "conditional rule shadows nothing",
+			cfg: Config{
+				DeleteRules:        []DeleteRule{{Kind: RuleSuffix, Pattern: ".tmp", MinSize: 1 << 20}},
+				ExtensionsToDelete: []string{".old.tmp"},
+			},
+		},
+		{
+			name: "chain",
+			cfg: Config{
+				ExtensionReplacements: map[string]string{".a": ".b", ".b": ".c"},
+			},
+			want: []string{`warning rename.extension_replacements ".a": renames to ".b"`},
+		},
+		{
+			name: "cycle",
+			cfg: Config{
+				ExtensionReplacements: map[string]string{".htm": ".html", ".html": ".htm"},
+			},
+			want: []string{`error rename.extension_replacements ".htm": replacements form a cycle (.htm → .html → .htm)`},
+		},
+		{
+			name: "invalid patterns",
+			cfg: Config{
+				DeleteRules: []DeleteRule{{Kind: RuleRegex, Pattern: "("}},
+				Excludes:    []string{"a/[b"},
+			},
+			want: []string{
+				`error delete.rules "(": `,
+				`error excludes "a/[b": `,
+			},
+		},
+		{
+			name: "empty suffix",
+			cfg:  Config{ExtensionsToDelete: []string{""}},
+			want: []string{`error delete.extensions: `},
+		},
+		{
+			name: "unknown collision policy",
+			cfg:  Config{RenameCollisions: "clobber"},
+			want: []string{`error rename.collisions: `},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := ValidateConfig(&tt.cfg)
+			require.Len(t, diags, len(tt.want), "diagnostics: %v", diags)
+			for _, want := range tt.want {
+				found := false
+				for _, d := range diags {
+					got := string(d.Severity) + " " + d.Setting
+					if d.Value != "" {
+						got += " " + `"` + d.Value + `"`
+					}
+					got += ": " + d.Message
+					if strings.HasPrefix(got, want) {
+						found = true
+						break
+					}
+				}
+				assert.True(t, found, "no diagnostic %s in %v", want, diags)
+			}
+		})
+	}
+}
+
+func TestValidateConfigLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "version: 1\n" +
+		"delete:\n" +
+		"  extensions:\n" +
+		"    - .log\n" +
+		"    - bak\n" +
+		"rename:\n" +
+		"  extension_replacements:\n" +
+		"    .jpeg: .jpg\n" +
+		"    .JPEG: .jpg\n"
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	file, err := ReadConfigFile(path)
+	require.NoError(t, err)
+	diags := ValidateConfig(file.Config(path))
+	require.Len(t, diags, 2, "diagnostics: %v", diags)
+	assert.Equal(t, Diagnostic{
+		Severity: SeverityWarning,
+		Setting:  "delete.extensions",
+		Value:    "bak",
+		Source:   path,
+		Line:     5,
+		Message:  diags[0].Message,
+	}, diags[0])
+	assert.Equal(t, 9, diags[1].Line)
+	assert.Contains(t, diags[1].String(), path+`:9: warning: rename.extension_replacements ".JPEG": `)
+}
+
+func TestLoadConfigValidates(t *testing.T) {
+	isolateConfig(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, DirConfigName)
+
+	writeConfig(t, path, `{"version": 1, "rename": {"extension_replacements": {"add": {"jpeg": ".jpg"}}}}`)
+	cfg, err := LoadConfig(LoadConfigOptions{Dir: dir})
+	require.NoError(t, err, "warnings do not fail the load")
+	require.Len(t, cfg.Warnings, 1)
+	assert.Equal(t, "jpeg", cfg.Warnings[0].Value)
+	assert.Equal(t, path, cfg.Warnings[0].Source)
+
+	writeConfig(t, path, `{"version": 1, "rename": {"extension_replacements": {"add": {".a": ".b", ".b": ".a"}}}}`)
+	_, err = LoadConfig(LoadConfigOptions{Dir: dir})
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, err.Error(), "cycle")
+	assert.NotEmpty(t, verr.Diagnostics)
+}
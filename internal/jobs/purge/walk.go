@@ -0,0 +1,232 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/spf13/afero"
+
+	"housekeeper/internal/common"
+)
+
+// DefaultWalkWorkers is the number of directories read at once when a Job
+// does not set Workers. Walks are mostly I/O bound, so it exceeds the CPUs.
+var DefaultWalkWorkers = max(4, 2*runtime.NumCPU())
+
+// walkEntry is a directory entry seen by walkDirs
+type walkEntry struct {
+	path     string
+	info     fs.FileInfo
+	excluded bool
+	change   *Change // planned for the file by the visit function, if any
+}
+
+// walkTree holds the sorted entries of every directory read by walkDirs
+type walkTree struct {
+	root    string
+	entries map[string][]walkEntry
+}
+
+// walkDirs reads the tree under root on fsys in a single pass, with up to
+// workers directories read at once. Excluded entries are recorded but
+// neither descended into nor visited. visit is called concurrently for
+// every other file and may return a change to plan for it. Directories that
+// cannot be read are reported and left out of the tree. Once ctx is done no
+// more files are visited and its error is returned. Scanned dirs and files
+// are reported to prog.
+func walkDirs(ctx context.Context, fsys afero.Fs, root string, ex *excluder, workers int, prog *progress, visit func(path string, info fs.FileInfo) *Change) (*walkTree, error) {
+	tree := &walkTree{root: root, entries: make(map[string][]walkEntry)}
+	var mu sync.Mutex // guards tree.entries
+
+	err := scanDirs(ctx, fsys, root, ex, workers, prog, visit, func(dir string, entries []walkEntry) {
+		mu.Lock()
+		tree.entries[dir] = entries
+		mu.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// scanDirs is walkDirs without the tree: dirDone is called concurrently
+// with the sorted entries of every directory once its files are visited,
+// and decides what to keep of them.
+func scanDirs(ctx context.Context, fsys afero.Fs, root string, ex *excluder, workers int, prog *progress, visit func(path string, info fs.FileInfo) *Change, dirDone func(dir string, entries []walkEntry)) error {
+	info, err := lstat(fsys, root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	if workers <= 0 {
+		workers = DefaultWalkWorkers
+	}
+
+	q := newDirQueue()
+	q.push(root)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				dir, ok := q.pop()
+				if !ok {
+					return
+				}
+				if ctx.Err() != nil {
+					q.done() // drain the queue without reading
+					continue
+				}
+				entries := readWalkDir(ctx, fsys, dir, ex, prog, visit, q)
+				if entries != nil && ctx.Err() == nil {
+					dirDone(dir, entries)
+				}
+				q.done()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// readWalkDir reads one directory, queues its subdirectories and visits its
+// files. It returns nil if the directory cannot be read.
+func readWalkDir(ctx context.Context, fsys afero.Fs, dir string, ex *excluder, prog *progress, visit func(string, fs.FileInfo) *Change, q *dirQueue) []walkEntry {
+	infos, err := afero.ReadDir(fsys, dir) // sorted by name
+	if err != nil {
+		common.Error.Printf("Accessing %s: %v", dir, err)
+		return nil
+	}
+	ex.enterDir(dir)
+	prog.dirScanned(dir)
+
+	entries := make([]walkEntry, 0, len(infos))
+	for _, info := range infos {
+		e := walkEntry{path: filepath.Join(dir, info.Name()), info: info}
+		e.excluded = ex.excluded(e.path, info.IsDir())
+
+		switch {
+		case e.excluded:
+		case info.IsDir():
+			q.push(e.path)
+		case ctx.Err() != nil:
+			// Canceled, the tree is thrown away
+		case info.Name() != IgnoreFileName && !isDirConfigName(info.Name()):
+			prog.fileScanned(e.path)
+			if visit != nil {
+				e.change = visit(e.path, info)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// changes returns the planned changes in depth-first, name-sorted order,
+// the order of a serial filepath.Walk
+func (t *walkTree) changes() []Change {
+	var changes []Change
+	var collect func(dir string)
+	collect = func(dir string) {
+		for _, e := range t.entries[dir] {
+			if e.change != nil {
+				changes = append(changes, *e.change)
+			}
+			if e.info.IsDir() && !e.excluded {
+				collect(e.path)
+			}
+		}
+	}
+	collect(t.root)
+	return changes
+}
+
+// dirContents maps every directory that was read to its children, keyed
+// by absolute path. Excluded entries count as children but have no entry of
+// their own, so neither they nor their parents are ever reported as empty.
+func (t *walkTree) dirContents() (map[string]map[string]bool, error) {
+	absRoot, err := filepath.Abs(t.root)
+	if err != nil {
+		return nil, err
+	}
+	abs := func(p string) string { return absPath(absRoot, t.root, p) }
+
+	dirContents := make(map[string]map[string]bool, len(t.entries))
+	for dir, entries := range t.entries {
+		children := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			children[abs(e.path)] = e.info.IsDir()
+		}
+		dirContents[abs(dir)] = children
+	}
+	return dirContents, nil
+}
+
+// emptyDirs returns the directories left empty once changes are applied
+func (t *walkTree) emptyDirs(changes []Change) ([]Change, error) {
+	dirContents, err := t.dirContents()
+	if err != nil {
+		return nil, err
+	}
+	simulateDeletions(changes, dirContents)
+	return detectEmptyDirs(dirContents), nil
+}
+
+// dirQueue is the work queue of walkDirs. It tracks directories that are
+// queued or being read, so workers stop once the whole tree is read.
+type dirQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	dirs    []string
+	pending int
+}
+
+func newDirQueue() *dirQueue {
+	q := &dirQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *dirQueue) push(dir string) {
+	q.mu.Lock()
+	q.dirs = append(q.dirs, dir)
+	q.pending++
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// pop waits for a directory to read. It returns false once the queue is
+// empty and no directory is being read anymore.
+func (q *dirQueue) pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.dirs) == 0 && q.pending > 0 {
+		q.cond.Wait()
+	}
+	if len(q.dirs) == 0 {
+		return "", false
+	}
+	dir := q.dirs[len(q.dirs)-1]
+	q.dirs = q.dirs[:len(q.dirs)-1]
+	return dir, true
+}
+
+// done marks a popped directory as read
+func (q *dirQueue) done() {
+	q.mu.Lock()
+	q.pending--
+	finished := q.pending == 0
+	q.mu.Unlock()
+	if finished {
+		q.cond.Broadcast()
+	}
+}
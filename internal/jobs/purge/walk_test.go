@@ -0,0 +1,106 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildWalkTestTree creates a tree with files to delete, rename and keep,
+// dirs that end up empty and an excluded dir
+func buildWalkTestTree(t *testing.T, fsys afero.Fs, root string) {
+	t.Helper()
+	for i := range 8 {
+		for j := range 6 {
+			dir := filepath.Join(root, fmt.Sprintf("d%d", i), fmt.Sprintf("s%d", j))
+			for k, name := range []string{"a.tmp", "b.JPG", "c.txt", "z.tmp"} {
+				if (i+j+k)%3 == 0 {
+					continue
+				}
+				require.NoError(t, afero.WriteFile(fsys, filepath.Join(dir, name), []byte(name), 0644))
+			}
+			require.NoError(t, fsys.MkdirAll(filepath.Join(dir, "empty", "deeper"), 0755))
+		}
+	}
+	require.NoError(t, afero.WriteFile(fsys, filepath.Join(root, "d3", IgnoreFileName), []byte("s2/\n"), 0644))
+}
+
+func TestPreviewChangesDeterministic(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	buildWalkTestTree(t, fsys, root)
+	cfg := &Config{ExtensionsToDelete: []string{".tmp"}}
+
+	want, err := previewChanges(context.Background(), fsys, root, cfg, 1, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, want)
+
+	for _, workers := range []int{2, 8, 32} {
+		for range 5 {
+			got, err := previewChanges(context.Background(), fsys, root, cfg, workers, nil)
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "workers = %d", workers)
+		}
+	}
+}
+
+func TestWalkDirsMatchesSerialWalk(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	buildWalkTestTree(t, fsys, root)
+
+	ex, err := newExcluder(fsys, root, nil)
+	require.NoError(t, err)
+	tree, err := walkDirs(context.Background(), fsys, root, ex, 8, nil, func(path string, info fs.FileInfo) *Change {
+		return &Change{Type: DeleteFile, Target: path}
+	})
+	require.NoError(t, err)
+
+	// A serial walk, skipping what the ignore file in d3 excludes
+	var want []string
+	excluded := filepath.Join(root, "d3", "s2")
+	require.NoError(t, afero.Walk(fsys, root, func(path string, info fs.FileInfo, err error) error {
+		if path == excluded {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && info.Name() != IgnoreFileName {
+			want = append(want, path)
+		}
+		return err
+	}))
+
+	var got []string
+	for _, c := range tree.changes() {
+		got = append(got, c.Target)
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestWalkDirsUnreadableRoot(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	_, err := walkDirs(context.Background(), fsys, "/missing", &excluder{}, 4, nil, nil)
+	assert.Error(t, err)
+
+	require.NoError(t, afero.WriteFile(fsys, "/file.txt", nil, 0644))
+	_, err = walkDirs(context.Background(), fsys, "/file.txt", &excluder{}, 4, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestPreviewChangesCanceled(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := filepath.Join(string(filepath.Separator), "scan")
+	buildWalkTestTree(t, fsys, root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	changes, err := previewChanges(ctx, fsys, root, &Config{ExtensionsToDelete: []string{".tmp"}}, 4, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, changes)
+}